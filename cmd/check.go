@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/BESTSELLER/harpocrates/vault"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that every secret and key in the spec can be read, without fetching them",
+	Long: `Check that every secret and key in the spec can be read, without fetching them.
+
+The capabilities of the token are checked for every secret, and the metadata and keys of KV v2 secrets are read
+to confirm that they exist. No values are read, so the check can run in a pull request against a staging Vault.
+It exits with 1 when a check fails.
+
+  harpocrates check -f secrets.yaml
+  harpocrates check --json -f secrets.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		skipWrites()
+		loadLocalVaultToken()
+
+		input, ok, err := loadSpec(cmd, args)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		if !ok {
+			return
+		}
+
+		if err := vault.Login(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to login to Vault")
+		}
+		results, err := vault.NewClient().CheckSecrets(input)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to check the secrets")
+		}
+
+		if checkJSON {
+			err = printJSON(results)
+		} else {
+			err = printCheckTable(results)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to print the results")
+		}
+
+		failed := 0
+		for _, result := range results {
+			if result.Status == vault.CheckFailed {
+				failed++
+			}
+		}
+		if failed > 0 {
+			log.Fatal().Msgf("%d of %d checks failed", failed, len(results))
+		}
+	},
+}
+
+var checkJSON bool
+
+func init() {
+	checkCmd.Flags().BoolVar(&checkJSON, "json", false, "print the results as json")
+
+	rootCmd.AddCommand(checkCmd)
+}
+
+// printCheckTable prints a row per result, with a dash as the key of the results about a whole secret
+func printCheckTable(results []vault.CheckResult) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tSECRET\tKEY\tMESSAGE")
+	for _, result := range results {
+		key := result.Key
+		if key == "" {
+			key = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Status, result.Path, key, result.Message)
+	}
+	return w.Flush()
+}
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"path"
 	"syscall"
+	"time"
 
 	"github.com/BESTSELLER/harpocrates/config"
 	"github.com/BESTSELLER/harpocrates/util"
@@ -45,30 +46,51 @@ This is useful for local development, where you want to run an application with
 
 		log.Info().Str("output", config.Config.Output).Send()
 
-		secretEnvs := doIt(cmd, args)
-
-		// Set up cancellable context and signal handling for ctrl+c
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
-		go func() {
-			<-signals
-			cleanup()
-			cancel()
-		}()
-
-		// Start the child application with the temporary file path using the context
-		execCmd := exec.CommandContext(ctx, args[0], args[1:]...)
-
-		finalEnvs := append(secretEnvs, fmt.Sprintf("SECRET_PATH=%s", config.Config.Output))
-		finalEnvs = append(os.Environ(), finalEnvs...)
-		execCmd.Env = finalEnvs
+		secretEnvs := []string{}
+		input, allSecrets, ok, err := loadSecrets(cmd, args)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		if ok {
+			secretEnvs, err = writeSecrets(cmd, allSecrets)
+			if err != nil {
+				cleanup()
+				log.Fatal().Err(err).Msg("Unable to write the secrets")
+			}
+		}
+		redactValues := secretValues(allSecrets)
+
+		finalEnvs := childEnv(secretEnvs)
+
+		if watch {
+			err = runWatched(cmd, args, input, allSecrets, secretEnvs)
+		} else if noPTY {
+			// Signals are forwarded to the child, which decides itself when to stop
+			execCmd := exec.Command(args[0], args[1:]...)
+			execCmd.Env = finalEnvs
+			err = util.RunCmd(execCmd, redactValues, redact)
+		} else {
+			// Set up cancellable context and signal handling for ctrl+c
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			go func() {
+				<-signals
+				cleanup()
+				cancel()
+			}()
+
+			// Start the child application with the temporary file path using the context
+			execCmd := exec.CommandContext(ctx, args[0], args[1:]...)
+			execCmd.Env = finalEnvs
+			err = util.RunCmdPTY(execCmd, redactValues, redact)
+		}
 
-		if err := util.RunCmdPTY(execCmd, secretEnvs, redact); err != nil {
+		if err != nil {
 			cleanup() // Clean up the temporary directory manually before os.Exit or log.Fatal since defer won't run
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				os.Exit(exitErr.ExitCode())
+			if exitCode, ok := util.ExitCode(err); ok {
+				os.Exit(exitCode)
 			}
 			log.Fatal().Err(err).Msg("Command execution failed")
 		}
@@ -76,10 +98,24 @@ This is useful for local development, where you want to run an application with
 	},
 }
 
-var redact bool
+var (
+	redact bool
+	noPTY  bool
+)
+
+// childEnv returns the environment of the command run by dev
+func childEnv(secretEnvs []string) []string {
+	finalEnvs := append(secretEnvs, fmt.Sprintf("SECRET_PATH=%s", config.Config.Output))
+	return append(os.Environ(), finalEnvs...)
+}
 
 func init() {
 	devCmd.PersistentFlags().BoolVar(&redact, "redact", false, "Redact secrets from output, defaults to false")
+	devCmd.PersistentFlags().BoolVar(&noPTY, "no-pty", false, "Run the command without a pseudo-terminal, keeping stdout and stderr apart and forwarding signals, defaults to false")
+	devCmd.PersistentFlags().BoolVar(&watch, "watch", false, "Restart the command with fresh secrets when the spec file or a secret in Vault changes, defaults to false")
+	devCmd.PersistentFlags().DurationVar(&watchInterval, "watch-interval", 30*time.Second, "How often Vault is checked for new versions of the secrets with --watch")
+	devCmd.PersistentFlags().StringVar(&watchSignal, "watch-signal", "SIGTERM", "Signal sent to stop the command before it is restarted with --watch")
+	devCmd.PersistentFlags().DurationVar(&watchGrace, "watch-grace", 10*time.Second, "How long the command gets to stop before it is killed with --watch")
 	devCmd.Flags().SetInterspersed(false)
 
 	rootCmd.AddCommand(devCmd)
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/files"
+	"github.com/BESTSELLER/harpocrates/secrets"
+	"github.com/BESTSELLER/harpocrates/vault"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the keys that differ between two specs, two profiles, or a spec and its output files",
+	Long: `Show the keys that differ between two specs, two profiles, or a spec and its output files.
+
+Without --against or --against-profile the output of the spec is compared with the files in the output folder,
+showing what a fetch would add, remove and change. With append, formats that can be merged are compared as the fetch
+would merge them, so keys only in the files are kept and not shown as removed.
+With --against or --against-profile, the secrets of both are fetched and compared,
+e.g. to see what differs between dev and prod before promoting. Nothing is written.
+Changed values are only shown as the start of their HMAC-SHA256, with a random key unless --hash-key or HARPOCRATES_HASH_KEY is given.
+
+  harpocrates diff -f secrets.yaml
+  harpocrates diff -f secrets.yaml --profile dev --against-profile prod
+  harpocrates diff -f dev.yaml --against prod.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		skipWrites()
+
+		var diffs []fileDiff
+		var ok bool
+		if diffAgainst != "" || diffAgainstProfile != "" {
+			diffs, ok = diffSpecs(cmd, args)
+		} else {
+			diffs, ok = diffFiles(cmd, args)
+		}
+		if !ok {
+			return
+		}
+
+		var err error
+		if diffJSON {
+			err = printJSON(diffs)
+		} else {
+			err = printDiffText(diffs)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to print the differences")
+		}
+
+		if diffExitCode && slices.ContainsFunc(diffs, func(diff fileDiff) bool { return len(diff.Changes) > 0 }) {
+			os.Exit(1)
+		}
+	},
+}
+
+var (
+	diffAgainst        string
+	diffAgainstProfile string
+	diffJSON           bool
+	diffExitCode       bool
+)
+
+func init() {
+	diffCmd.Flags().StringVar(&diffAgainst, "against", "", "spec file to compare with, defaults to the spec given by --file")
+	diffCmd.Flags().StringVar(&diffAgainstProfile, "against-profile", "", "profile to compare with, defaults to the profile given by --profile")
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "print the differences as json")
+	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "exit with 1 when there are differences")
+	diffCmd.Flags().StringVar(&hashKey, "hash-key", "", "key to hash the values with, defaults to "+hashKeyEnv+" or a random key")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+// fileDiff holds the changed keys of a file, or of all the secrets of a spec
+type fileDiff struct {
+	Name    string       `json:"name"`
+	Missing bool         `json:"missing,omitempty"`
+	Changes []diffChange `json:"changes"`
+}
+
+// diffChange is a changed key, with its values as hashes
+type diffChange struct {
+	Key  string             `json:"key"`
+	Kind secrets.ChangeKind `json:"kind"`
+	Old  string             `json:"old,omitempty"`
+	New  string             `json:"new,omitempty"`
+}
+
+func newFileDiff(name string, changes []secrets.Change) fileDiff {
+	diff := fileDiff{Name: name, Changes: []diffChange{}}
+	for _, change := range changes {
+		hashed := diffChange{Key: change.Key, Kind: change.Kind}
+		if change.Kind != secrets.ChangeAdded {
+			hashed.Old = hashValue(change.Old)
+		}
+		if change.Kind != secrets.ChangeRemoved {
+			hashed.New = hashValue(change.New)
+		}
+		diff.Changes = append(diff.Changes, hashed)
+	}
+	return diff
+}
+
+// diffSpecs fetches the secrets of the spec and of the spec or profile to compare with, and compares all their keys
+func diffSpecs(cmd *cobra.Command, args []string) ([]fileDiff, bool) {
+	// Reading a spec changes the config, so both specs start from the flags
+	flags := config.Config
+
+	oldName := specName(secretFile, config.Config.Profile)
+	oldSecrets, ok := extractSecrets(cmd, args)
+	if !ok {
+		return nil, false
+	}
+
+	config.Config = flags
+	if diffAgainst != "" {
+		secretFile = diffAgainst
+	}
+	if diffAgainstProfile != "" {
+		config.Config.Profile = diffAgainstProfile
+	}
+	newName := specName(secretFile, config.Config.Profile)
+	newSecrets, ok := extractSecrets(cmd, args)
+	if !ok {
+		return nil, false
+	}
+
+	changes := secrets.Diff(allResults(oldSecrets), allResults(newSecrets))
+	return []fileDiff{newFileDiff(fmt.Sprintf("%s -> %s", oldName, newName), changes)}, true
+}
+
+// diffFiles compares the files the outputs of the spec would be written to with the files in the output folder.
+// Formats that can't be read back are compared as a whole, with the file name as the key.
+func diffFiles(cmd *cobra.Command, args []string) ([]fileDiff, bool) {
+	allSecrets, ok := extractSecrets(cmd, args)
+	if !ok {
+		return nil, false
+	}
+	if config.Config.Output == files.Stdout {
+		log.Fatal().Msg("There are no files to compare with when the output is stdout, use --against or --against-profile to compare specs")
+	}
+
+	// The files are compared as a fetch would write them, merged with the existing files when appending
+	pending, _, err := renderSecrets(allSecrets, readCurrentFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Unable to render the secrets")
+	}
+
+	var diffs []fileDiff
+	for _, file := range pending {
+		parse, _ := secrets.GetParser(file.format)
+		diffs = append(diffs, diffFile(file.name, file.content, file.options, parse))
+	}
+	return diffs, true
+}
+
+// diffFile compares the content a file would be written with to the content it has, reading both with parse if it is given
+func diffFile(fileName string, content any, options files.Options, parse secrets.Parser) fileDiff {
+	path := filepath.Join(config.Config.Output, files.ResolveFileName(fileName, options))
+	existing, found, err := files.ReadCurrent(config.Config.Output, fileName, options)
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+
+	oldResult, newResult := secrets.Result{}, secrets.Result{}
+	if parse == nil {
+		if found {
+			oldResult[filepath.Base(path)] = existing
+		}
+		newResult[filepath.Base(path)] = content
+	} else {
+		if found {
+			if oldResult, err = parse(existing); err != nil {
+				log.Fatal().Err(err).Msgf("Unable to read the file '%s'", path)
+			}
+		}
+		if newResult, err = parse(fmt.Sprint(content)); err != nil {
+			log.Fatal().Err(err).Msgf("Unable to read the output for '%s'", path)
+		}
+	}
+
+	diff := newFileDiff(path, secrets.Diff(oldResult, newResult))
+	diff.Missing = !found
+	return diff
+}
+
+// allResults combines the keys of all outputs with the values saved as files, which are keyed by their file name
+func allResults(allSecrets []vault.Outputs) secrets.Result {
+	result, _, err := vault.CombineResults(allSecrets)
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	for _, output := range allSecrets {
+		maps.Copy(result, output.Files)
+	}
+	return result
+}
+
+// specName describes a spec and profile for the header of a diff
+func specName(specFile string, profile string) string {
+	name := specFile
+	if name == "" {
+		name = "inline spec"
+	}
+	if profile != "" {
+		name = fmt.Sprintf("%s (profile %s)", name, profile)
+	}
+	return name
+}
+
+// printDiffText prints the changes of every file that has any, added keys with a +, removed keys with a - and changed keys with a ~
+func printDiffText(diffs []fileDiff) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	counts := map[secrets.ChangeKind]int{}
+	for _, diff := range diffs {
+		if len(diff.Changes) == 0 {
+			continue
+		}
+		if counts[secrets.ChangeAdded]+counts[secrets.ChangeRemoved]+counts[secrets.ChangeChanged] > 0 {
+			fmt.Fprintln(w)
+		}
+		if diff.Missing {
+			fmt.Fprintf(w, "%s (missing)\n", diff.Name)
+		} else {
+			fmt.Fprintln(w, diff.Name)
+		}
+		for _, change := range diff.Changes {
+			counts[change.Kind]++
+			switch change.Kind {
+			case secrets.ChangeAdded:
+				fmt.Fprintf(w, "  + %s\t%s\n", change.Key, change.New)
+			case secrets.ChangeRemoved:
+				fmt.Fprintf(w, "  - %s\t%s\n", change.Key, change.Old)
+			default:
+				fmt.Fprintf(w, "  ~ %s\t%s -> %s\n", change.Key, change.Old, change.New)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(counts) == 0 {
+		fmt.Println("No differences")
+		return nil
+	}
+	fmt.Printf("\n%d added, %d removed, %d changed\n", counts[secrets.ChangeAdded], counts[secrets.ChangeRemoved], counts[secrets.ChangeChanged])
+	return nil
+}
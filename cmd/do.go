@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/BESTSELLER/harpocrates/config"
 	"github.com/BESTSELLER/harpocrates/files"
+	"github.com/BESTSELLER/harpocrates/secrets"
 	"github.com/BESTSELLER/harpocrates/util"
 	"github.com/BESTSELLER/harpocrates/validate"
 	"github.com/BESTSELLER/harpocrates/vault"
@@ -34,34 +40,65 @@ func loadLocalVaultToken() {
 
 }
 
-func doIt(cmd *cobra.Command, args []string) []string {
+// extractSecrets reads the spec given by the flags or arguments and fetches its secrets from Vault.
+// It returns false when there is nothing more to do, e.g. when only validating the spec.
+func extractSecrets(cmd *cobra.Command, args []string) ([]vault.Outputs, bool) {
+	_, allSecrets, ok, err := loadSecrets(cmd, args)
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	return allSecrets, ok
+}
+
+// loadSecrets is extractSecrets returning errors instead of exiting, along with the spec that was read
+func loadSecrets(cmd *cobra.Command, args []string) (util.SecretJSON, []vault.Outputs, bool, error) {
 	loadLocalVaultToken()
 
-	secretEnvs := []string{}
+	input, ok, err := loadSpec(cmd, args)
+	if !ok || err != nil {
+		return input, nil, false, err
+	}
+
+	err = vault.Login()
+	if err != nil {
+		return input, nil, false, fmt.Errorf("failed to login to Vault: %w", err)
+	}
+
+	vaultClient := vault.NewClient()
 
-	var data string
+	allSecrets, err := vaultClient.ExtractSecrets(input)
+	if err != nil {
+		return input, nil, false, fmt.Errorf("failed to extract secrets from Vault: %w", err)
+	}
+	return input, allSecrets, true, nil
+}
+
+// loadSpec reads and validates the spec given by the flags or arguments.
+// It returns false when there is nothing more to do, e.g. when only validating the spec.
+func loadSpec(cmd *cobra.Command, args []string) (util.SecretJSON, bool, error) {
 	var input util.SecretJSON
 
 	if secretFile != "" {
-		var err error
-		data, err = files.Read(secretFile)
+		data, err := files.Read(secretFile)
 		if err != nil {
-			log.Fatal().Err(err).Msgf("Unable to read the file at path '%s'", secretFile)
+			return input, false, err
 		}
 
-		validFile := validate.SecretsFile(data)
-		if !validFile {
-			log.Fatal().Msg("Invalid file")
+		if err := checkSpec(data, secretFile); err != nil {
+			return input, false, err
 		}
 		if config.Config.Validate {
-			return secretEnvs
+			return input, false, nil
+		}
+		input, err = util.LoadInput(data, secretFile)
+		if err != nil {
+			return input, false, err
 		}
-		input = util.ReadInput(data)
 	} else if len(*secret) > 0 {
 		if config.Config.Output == "" {
 			log.Error().Msg("Output is required!")
 			cmd.Usage() //nolint:errcheck // We don't care about errors from this
-			return secretEnvs
+			return input, false, nil
 		}
 
 		secretItems := make([]any, len(*secret))
@@ -76,54 +113,278 @@ func doIt(cmd *cobra.Command, args []string) []string {
 	} else {
 		if len(args) == 0 {
 			cmd.Help() //nolint:errcheck // We don't care about errors from this
-			return secretEnvs
+			return input, false, nil
 		}
 
-		if validate.SecretsFile(args[0]) {
-			input = util.ReadInput(args[0])
+		if err := checkSpec(args[0], ""); err != nil {
+			return input, false, err
 		}
 		if config.Config.Validate {
-			return secretEnvs
+			return input, false, nil
 		}
+		input, err := util.LoadInput(args[0], "")
+		if err != nil {
+			return input, false, err
+		}
+		return input, true, nil
 	}
+	return input, true, nil
+}
 
-	err := vault.Login()
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed to login to Vault")
+// checkSpec validates a spec and the specs it includes, returning an error if it has problems.
+// With --validate the report is printed in the --validate-format, otherwise the problems are printed as text to stderr.
+func checkSpec(data string, specFile string) error {
+	report := validate.Check(data, specFile)
+	if config.Config.Validate && config.Config.ValidateFormat != "" {
+		out, err := report.Format(config.Config.ValidateFormat)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+	} else if !report.Valid() {
+		fmt.Fprint(os.Stderr, report.Text())
 	}
 
-	vaultClient := vault.NewClient()
+	if report.Valid() {
+		return nil
+	}
+	if specFile == "" {
+		return fmt.Errorf("invalid spec")
+	}
+	return fmt.Errorf("invalid file '%s'", specFile)
+}
+
+// skipWrites keeps a command that only reads the secrets from writing any file
+func skipWrites() {
+	files.SkipWrites = true
+	// Nothing is written, but the --secret flag requires an output
+	if len(*secret) > 0 && config.Config.Output == "" {
+		config.Config.Output = files.Stdout
+	}
+}
 
-	allSecrets, err := vaultClient.ExtractSecrets(input, config.Config.Append)
+func doIt(cmd *cobra.Command, args []string) []string {
+	allSecrets, ok := extractSecrets(cmd, args)
+	if !ok {
+		return []string{}
+	}
+	secretEnvs, err := writeSecrets(cmd, allSecrets)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to extract secrets from Vault")
+		log.Fatal().Err(err).Msg("Unable to write the secrets")
 	}
+	return secretEnvs
+}
 
-	if cmd.Flags().Changed("format") && (config.Config.Format != "json" && config.Config.Format != "env" && config.Config.Format != "secret" && config.Config.Format != "yaml") {
-		log.Error().Msg("Please use a valid format of either: json, env, secret or yaml")
+// outputFormatOptions returns the options an output is formatted with
+func outputFormatOptions(output vault.Outputs, keyOrder string) secrets.FormatOptions {
+	formatOptions := secrets.FormatOptions{Template: output.Template, Nested: output.Nested}
+	if keyOrder == secrets.OrderSpec {
+		formatOptions.Order = output.Order
+	}
+	return formatOptions
+}
+
+// outputFileOptions returns the options the file of an output is written with
+func outputFileOptions(output vault.Outputs) files.Options {
+	return files.Options{Owner: output.Owner, Group: output.Group, Mode: output.Mode}
+}
+
+// outputFileName returns the name of the file an output is written to
+func outputFileName(output vault.Outputs) string {
+	if output.Filename != "" {
+		return output.Filename
+	}
+	return config.Config.FileName
+}
+
+// secretValues returns every fetched value, including the ones saved as files, so they can be redacted
+func secretValues(allSecrets []vault.Outputs) []string {
+	var values []string
+	for _, output := range allSecrets {
+		values = append(values, output.Result.Values()...)
+		values = append(values, output.Files.Values()...)
+	}
+	return values
+}
+
+// writeSecrets writes the secrets to the output in their format and returns the secrets in the env format as KEY=value pairs
+func writeSecrets(cmd *cobra.Command, allSecrets []vault.Outputs) ([]string, error) {
+	if cmd.Flags().Changed("format") && !secrets.IsFormat(config.Config.Format) {
+		log.Error().Msgf("Please use a valid format of either: %s", strings.Join(secrets.Formats(), ", "))
 		cmd.Help() //nolint:errcheck // We don't care about errors from this
-		return secretEnvs
+		return []string{}, nil
+	}
+
+	pending, secretEnvs, err := renderSecrets(allSecrets, readCurrentFile)
+	if err != nil {
+		return nil, err
+	}
+	writeFiles(pending)
+	return secretEnvs, nil
+}
+
+// printJSON prints the items as indented json, nil as an empty list
+func printJSON[T any](items []T) error {
+	if items == nil {
+		items = []T{}
+	}
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
 	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// pendingFile is a file rendered by renderSecrets that is not written yet
+type pendingFile struct {
+	name string
+	// format is the format of the content, it is empty for values saved as files
+	format  string
+	content any
+	options files.Options
+	// result holds the keys in the file and their values, in the order of keys, so a plan can list them.
+	// A key without a source comes from the existing file it is merged with.
+	result  secrets.Result
+	keys    []string
+	sources map[string]secrets.Source
+}
 
+// add puts the keys of a result after the keys the file has, like appending its content does
+func (file *pendingFile) add(result secrets.Result, keys []string, sources map[string]secrets.Source) {
+	for _, key := range keys {
+		if _, exists := file.result[key]; !exists {
+			file.keys = append(file.keys, key)
+		}
+		file.result[key] = result[key]
+		if source, ok := sources[key]; ok {
+			file.sources[key] = source
+		}
+	}
+}
+
+// currentFileReader returns the content of a file in the output, the bool is false if the file doesn't exist
+type currentFileReader func(fileName string, options files.Options) (string, bool, error)
+
+// readCurrentFile reads the files as they are in the output right now
+func readCurrentFile(fileName string, options files.Options) (string, bool, error) {
+	return files.ReadCurrent(config.Config.Output, fileName, options)
+}
+
+// noCurrentFiles reads every file as missing, so nothing is merged with what an earlier run wrote
+func noCurrentFiles(string, files.Options) (string, bool, error) {
+	return "", false, nil
+}
+
+// renderSecrets formats the outputs and the values saved as files without writing anything, so every error is found before a file changes.
+// Formats that can be read back are merged with the existing file when appending. Outputs sharing a file are combined in the order they are listed,
+// as if they were written one after the other. It also returns the secrets in the env format as KEY=value pairs.
+func renderSecrets(allSecrets []vault.Outputs, readCurrent currentFileReader) ([]*pendingFile, []string, error) {
+	keyOrder, err := secrets.ParseKeyOrder(config.Config.Order)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid order: %w", err)
+	}
+
+	conflictPolicy, err := secrets.ParseConflictPolicy(config.Config.Conflict, secrets.MergePolicies, secrets.PolicyLastWins)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid conflict policy: %w", err)
+	}
+
+	secretEnvs := []string{}
+	var pending []*pendingFile
+	byName := map[string]*pendingFile{}
 	for _, output := range allSecrets {
-		fileName := config.Config.FileName
-		if output.Filename != "" {
-			fileName = output.Filename
+		fileName := outputFileName(output)
+
+		formatter, ok := secrets.GetFormatter(output.Format)
+		if !ok {
+			log.Error().Msgf("Unknown format '%s', please use either: %s", output.Format, strings.Join(secrets.Formats(), ", "))
+			continue
+		}
+		formatOptions := outputFormatOptions(output, keyOrder)
+		fileOptions := outputFileOptions(output)
+
+		// Printed outputs are never combined, they are printed one after the other
+		previous := byName[files.ResolveFileName(fileName, fileOptions)]
+		if config.Config.Output == files.Stdout {
+			previous = nil
+		}
+
+		// Formats that can be read back are merged with the existing file instead of appended to
+		result := output.Result
+		mergeable := config.Config.Append && config.Config.Output != files.Stdout && secrets.CanMerge(output.Format)
+		if mergeable {
+			existing, found := "", false
+			if previous != nil {
+				existing, found = fmt.Sprint(previous.content), true
+			} else if existing, found, err = readCurrent(fileName, fileOptions); err != nil {
+				return nil, nil, err
+			}
+			if found {
+				result, err = secrets.Merge(output.Format, existing, result, formatOptions, conflictPolicy)
+				if err != nil {
+					return nil, nil, fmt.Errorf("unable to merge secrets into '%s': %w", fileName, err)
+				}
+			}
 		}
 
-		switch output.Format {
-		case "json":
-			files.Write(config.Config.Output, fileName, output.Result.ToJSON(), output.Owner, config.Config.Append)
-		case "env":
-			files.Write(config.Config.Output, fileName, output.Result.ToENV(), output.Owner, config.Config.Append)
+		content, err := formatter(result, formatOptions)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to export secrets as %s: %w", output.Format, err)
+		}
+		keys := result.Keys(formatOptions.Order)
+		switch {
+		case previous == nil:
+			file := &pendingFile{name: fileName, format: output.Format, content: content, options: fileOptions, result: secrets.Result{}, sources: map[string]secrets.Source{}}
+			file.add(result, keys, output.Sources)
+			pending = append(pending, file)
+			byName[files.ResolveFileName(fileName, fileOptions)] = file
+		case config.Config.Append && !mergeable:
+			previous.content = fmt.Sprint(previous.content) + content
+			previous.add(result, keys, output.Sources)
+		default:
+			// A merged result has the keys of the file so far, which keep their sources
+			sources := map[string]secrets.Source{}
+			if mergeable {
+				sources = previous.sources
+			}
+			previous.format, previous.content, previous.options = output.Format, content, fileOptions
+			previous.result, previous.keys, previous.sources = secrets.Result{}, nil, sources
+			previous.add(result, keys, output.Sources)
+		}
+
+		if output.Format == "env" {
 			secretEnvs = append(secretEnvs, output.Result.ToKVarray("")...)
-		case "secret":
-			files.Write(config.Config.Output, fileName, output.Result.ToK8sSecret(), output.Owner, config.Config.Append)
-		case "yaml":
-			files.Write(config.Config.Output, fileName, output.Result.ToYAML(), output.Owner, config.Config.Append)
 		}
-		log.Debug().Msgf("Secrets written to file: %s/%s", config.Config.Output, fileName)
 	}
 
-	return secretEnvs
+	for _, output := range allSecrets {
+		for _, fileName := range slices.Sorted(maps.Keys(output.SavedFiles)) {
+			// A value saved as a file would be printed without anything telling where it starts, so it is left out
+			if config.Config.Output == files.Stdout {
+				log.Warn().Msgf("Skipping '%s', keys with saveAsFile are not printed when the output is stdout", fileName)
+				continue
+			}
+			saved := output.SavedFiles[fileName]
+			key := filepath.Base(files.ResolveFileName(fileName, saved.Options))
+			pending = append(pending, &pendingFile{
+				name:    fileName,
+				content: output.Files[fileName],
+				options: saved.Options,
+				result:  secrets.Result{key: output.Files[fileName]},
+				keys:    []string{key},
+				sources: map[string]secrets.Source{key: saved.Source},
+			})
+		}
+	}
+	return pending, secretEnvs, nil
+}
+
+// writeFiles writes the files rendered by renderSecrets and makes them visible at once with the symlink swap layout
+func writeFiles(pending []*pendingFile) {
+	for _, file := range pending {
+		files.Write(config.Config.Output, file.name, file.content, file.options, false)
+		log.Debug().Msgf("Secrets written to file: %s/%s", config.Config.Output, file.name)
+	}
+	files.Commit()
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/secrets"
+	"github.com/BESTSELLER/harpocrates/vault"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print secrets as shell commands that set environment variables",
+	Long: `Print secrets as shell commands that set environment variables.
+
+The env command fetches secrets from Vault and prints them for a shell to evaluate, nothing is written to disk.
+Keys saved as files with saveAsFile are skipped.
+
+  bash/zsh:    eval "$(harpocrates env -f secrets.yaml)"
+  fish:        harpocrates env -f secrets.yaml --shell fish | source
+  PowerShell:  harpocrates env -f secrets.yaml --shell powershell | Out-String | Invoke-Expression
+  direnv:      eval "$(harpocrates env -f secrets.yaml --shell bash)" in .envrc`,
+	Run: func(cmd *cobra.Command, args []string) {
+		shell := envShell
+		if shell == "" {
+			shell = secrets.DetectShell(os.Getenv("SHELL"))
+		}
+		if !slices.Contains(secrets.Shells(), shell) {
+			log.Fatal().Msgf("Unknown shell '%s', please use either: %s", shell, strings.Join(secrets.Shells(), ", "))
+		}
+
+		skipWrites()
+
+		allSecrets, ok := extractSecrets(cmd, args)
+		if !ok {
+			return
+		}
+
+		keyOrder, err := secrets.ParseKeyOrder(config.Config.Order)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid order")
+		}
+		combined, order, err := vault.CombineResults(allSecrets)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		if keyOrder != secrets.OrderSpec {
+			order = nil
+		}
+		exports, err := combined.ToShell(shell, order)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		fmt.Print(exports)
+	},
+}
+
+var envShell string
+
+func init() {
+	envCmd.Flags().StringVar(&envShell, "shell", "", "shell to print the commands for, either bash, fish, nushell, powershell or zsh, defaults to the shell in $SHELL")
+
+	rootCmd.AddCommand(envCmd)
+}
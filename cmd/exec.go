@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/util"
+	"github.com/BESTSELLER/harpocrates/vault"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec [spec] command [args...]",
+	Short: "Replace harpocrates with a command that has secrets in its environment",
+	Long: `Replace harpocrates with a command that has secrets in its environment.
+
+The exec command fetches secrets from Vault, sets them as environment variables and then replaces itself with the command.
+The command keeps the process ID, so it runs as PID 1 in a container and receives signals directly. Its output is not redirected or redacted.
+This makes it usable as the ENTRYPOINT of a container, instead of an init container and a shared volume.
+
+All secrets are set as environment variables regardless of their format. Use --write-files to also write them to the output like fetch does.
+
+  harpocrates exec -f /secrets.yaml -- node server.js
+  harpocrates exec '{"secrets":["secret/data/app"]}' node server.js`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Without a spec file or --secret flag, the first argument is the inline spec
+		specArgs, command := []string{}, args
+		if secretFile == "" && len(*secret) == 0 && len(args) > 0 {
+			specArgs, command = args[:1], args[1:]
+		}
+		if len(command) == 0 {
+			log.Fatal().Msg("No command provided to execute")
+		}
+
+		if !execWriteFiles {
+			skipWrites()
+		}
+
+		allSecrets, ok := extractSecrets(cmd, specArgs)
+		if !ok {
+			return
+		}
+
+		env := os.Environ()
+		if execWriteFiles {
+			if _, err := writeSecrets(cmd, allSecrets); err != nil {
+				log.Fatal().Err(err).Msg("Unable to write the secrets")
+			}
+			env = append(env, fmt.Sprintf("SECRET_PATH=%s", config.Config.Output))
+		}
+		combined, _, err := vault.CombineResults(allSecrets)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		env = append(env, combined.ToKVarray("")...)
+
+		if err := util.Exec(command, env); err != nil {
+			log.Fatal().Err(err).Msgf("Unable to execute '%s'", command[0])
+		}
+	},
+}
+
+var execWriteFiles bool
+
+func init() {
+	execCmd.Flags().BoolVar(&execWriteFiles, "write-files", false, "also write the secrets to the output like fetch does, defaults to false")
+	execCmd.Flags().SetInterspersed(false)
+
+	rootCmd.AddCommand(execCmd)
+}
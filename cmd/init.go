@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/secrets"
+	"github.com/BESTSELLER/harpocrates/util"
+	"github.com/BESTSELLER/harpocrates/validate"
+	"github.com/BESTSELLER/harpocrates/vault"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultSpecFile is where init writes the spec when no --file is given
+const defaultSpecFile = "secrets.yaml"
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a spec by picking secrets and keys in Vault",
+	Long: `Create a spec by picking secrets and keys in Vault.
+
+In a terminal the mounts and paths of Vault are browsed like the completion of the LSP does,
+and the secrets and keys picked are written to a commented spec along with the chosen format and output.
+With --from the secrets are given instead, and every key of them is listed with --keys-all.
+The spec is written to the file given by --file, secrets.yaml by default.
+
+  harpocrates init
+  harpocrates init --from kv/data/app/dev --keys-all -f secrets.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		specFile := secretFile
+		if specFile == "" {
+			specFile = defaultSpecFile
+		}
+		if _, err := os.Stat(specFile); err == nil && !initForce {
+			log.Fatal().Msgf("The file '%s' already exists, use --force to overwrite it", specFile)
+		}
+
+		loadLocalVaultToken()
+		if err := vault.Login(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to login to Vault")
+		}
+		vaultClient := vault.NewClient()
+
+		scaffold := util.Scaffold{Format: config.Config.Format, Output: config.Config.Output}
+		if scaffold.Format == "" {
+			scaffold.Format = "env"
+		}
+		if scaffold.Output == "" {
+			scaffold.Output = "/secrets"
+		}
+
+		var err error
+		switch {
+		case len(initFrom) > 0:
+			scaffold.Secrets, err = secretsFrom(vaultClient, initFrom, initKeysAll)
+		case term.IsTerminal(int(os.Stdin.Fd())):
+			err = newPicker(vaultClient, os.Stdin, os.Stdout).pick(&scaffold)
+		default:
+			log.Fatal().Msg("Use --from to give the secrets when not running in a terminal")
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to pick the secrets")
+		}
+		if len(scaffold.Secrets) == 0 {
+			log.Fatal().Msg("No secrets were picked, nothing to write")
+		}
+
+		spec, err := scaffold.Render()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to create the spec")
+		}
+		if report := validate.Check(spec, specFile); !report.Valid() {
+			log.Fatal().Msgf("The created spec is not valid:\n%s", report.Text())
+		}
+		if err := os.WriteFile(specFile, []byte(spec), 0644); err != nil {
+			log.Fatal().Err(err).Msgf("Unable to write the spec to '%s'", specFile)
+		}
+		log.Info().Msgf("Wrote the spec to '%s', fetch the secrets with: harpocrates fetch -f %s", specFile, specFile)
+	},
+}
+
+var (
+	initFrom    []string
+	initKeysAll bool
+	initForce   bool
+)
+
+func init() {
+	initCmd.Flags().StringSliceVar(&initFrom, "from", []string{}, "secrets to add without browsing Vault, e.g. kv/data/app/dev,kv/data/app/shared")
+	initCmd.Flags().BoolVar(&initKeysAll, "keys-all", false, "list every key of the secrets given by --from, instead of fetching the whole secrets")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite the spec file if it exists")
+
+	rootCmd.AddCommand(initCmd)
+}
+
+// secretsFrom returns the secrets for the given paths, listing their keys when keysAll is set
+func secretsFrom(vaultClient *vault.API, paths []string, keysAll bool) ([]util.ScaffoldSecret, error) {
+	var picked []util.ScaffoldSecret
+	for _, path := range paths {
+		secret := util.ScaffoldSecret{Path: path}
+		if keysAll {
+			keys, err := vaultClient.SecretKeys(path)
+			if err != nil {
+				return nil, err
+			}
+			secret.Keys = keys
+		}
+		picked = append(picked, secret)
+	}
+	return picked, nil
+}
+
+// picker asks which secrets, keys, format and output to use, browsing Vault one path at a time
+type picker struct {
+	vaultClient *vault.API
+	in          *bufio.Scanner
+	out         io.Writer
+}
+
+func newPicker(vaultClient *vault.API, in io.Reader, out io.Writer) *picker {
+	return &picker{vaultClient: vaultClient, in: bufio.NewScanner(in), out: out}
+}
+
+// pick fills in the secrets, format and output of the scaffold
+func (p *picker) pick(scaffold *util.Scaffold) error {
+	path := ""
+	for {
+		entries, err := vault.ListPath(p.vaultClient, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(p.out, "\n%s\n", displayPath(path))
+		for i, entry := range entries {
+			fmt.Fprintf(p.out, "  %d) %s\n", i+1, entry)
+		}
+		answer, ok := p.ask(fmt.Sprintf("Pick a number to open a folder or add a secret, '..' to go back, or 'done' with %d picked", len(scaffold.Secrets)), "")
+		switch {
+		case !ok || answer == "done":
+			if len(scaffold.Secrets) > 0 {
+				p.pickOutput(scaffold)
+			}
+			return nil
+		case answer == "..":
+			path = parentPath(path)
+			continue
+		}
+
+		index, err := strconv.Atoi(answer)
+		if err != nil || index < 1 || index > len(entries) {
+			fmt.Fprintf(p.out, "'%s' is not one of the numbers\n", answer)
+			continue
+		}
+		entry := path + entries[index-1]
+		if strings.HasSuffix(entry, "/") {
+			path = entry
+			continue
+		}
+
+		secret, err := p.pickKeys(entry)
+		if err != nil {
+			return err
+		}
+		scaffold.Secrets = append(scaffold.Secrets, secret)
+	}
+}
+
+// pickKeys asks which keys of a secret to add, none adds the whole secret
+func (p *picker) pickKeys(path string) (util.ScaffoldSecret, error) {
+	secret := util.ScaffoldSecret{Path: path}
+	keys, err := p.vaultClient.SecretKeys(path)
+	if err != nil {
+		return secret, err
+	}
+
+	fmt.Fprintf(p.out, "\nKeys of %s\n", path)
+	for i, key := range keys {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, key)
+	}
+	for {
+		answer, _ := p.ask("Pick the keys to add, e.g. 1,3, 'all' to list every key, or nothing to fetch the whole secret", "")
+		switch answer {
+		case "":
+			return secret, nil
+		case "all":
+			secret.Keys = keys
+			return secret, nil
+		}
+
+		secret.Keys = nil
+		valid := true
+		for _, number := range strings.Split(answer, ",") {
+			index, err := strconv.Atoi(strings.TrimSpace(number))
+			if err != nil || index < 1 || index > len(keys) {
+				fmt.Fprintf(p.out, "'%s' is not one of the numbers\n", strings.TrimSpace(number))
+				valid = false
+				break
+			}
+			secret.Keys = append(secret.Keys, keys[index-1])
+		}
+		if valid {
+			return secret, nil
+		}
+	}
+}
+
+// pickOutput asks for the format and output folder, defaulting to the ones given by the flags
+func (p *picker) pickOutput(scaffold *util.Scaffold) {
+	for {
+		answer, ok := p.ask(fmt.Sprintf("Format, one of %s", strings.Join(secrets.Formats(), ", ")), scaffold.Format)
+		if secrets.IsFormat(answer) || !ok {
+			scaffold.Format = answer
+			break
+		}
+		fmt.Fprintf(p.out, "'%s' is not a format\n", answer)
+	}
+	scaffold.Output, _ = p.ask("Folder to write the files to", scaffold.Output)
+}
+
+// ask prints the question and returns the trimmed answer, or the default for an empty answer.
+// It returns false when there is no more input.
+func (p *picker) ask(question string, defaultAnswer string) (string, bool) {
+	if defaultAnswer != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultAnswer)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.in.Scan() {
+		return defaultAnswer, false
+	}
+	answer := strings.TrimSpace(p.in.Text())
+	if answer == "" {
+		return defaultAnswer, true
+	}
+	return answer, true
+}
+
+// displayPath shows the root of Vault as a slash
+func displayPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// parentPath returns the path one folder up, e.g. kv/data/app/ becomes kv/data/
+func parentPath(path string) string {
+	trimmed := strings.TrimSuffix(path, "/")
+	index := strings.LastIndex(trimmed, "/")
+	if index < 0 {
+		return ""
+	}
+	return trimmed[:index+1]
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/BESTSELLER/harpocrates/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [spec files]",
+	Short: "Upgrade secrets files to the latest apiVersion",
+	Long: `Upgrade secrets files to the latest apiVersion.
+
+The files are rewritten in place, keeping their comments, blank lines and the order of their options.
+Other formatting is normalized, e.g. yaml is indented by two spaces and json is written indented.
+Files given with -f or as arguments are migrated, included specs are not, so list them too.
+
+  harpocrates migrate -f secrets.yaml
+  harpocrates migrate secrets.yaml shared/*.yaml
+  harpocrates migrate --stdout -f secrets.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		specFiles := args
+		if secretFile != "" {
+			specFiles = append([]string{secretFile}, specFiles...)
+		}
+		if len(specFiles) == 0 {
+			cmd.Help() //nolint:errcheck // We don't care about errors from this
+			return
+		}
+
+		for _, specFile := range specFiles {
+			if err := migrateFile(specFile); err != nil {
+				log.Fatal().Err(err).Str("file", specFile).Msg("Unable to migrate the secrets file")
+			}
+		}
+	},
+}
+
+var migrateStdout bool
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateStdout, "stdout", false, "print the migrated specs instead of rewriting the files")
+
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// migrateFile rewrites a spec file at the latest apiVersion
+func migrateFile(specFile string) error {
+	info, err := os.Stat(specFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(specFile)
+	if err != nil {
+		return err
+	}
+
+	migrated, version, err := util.MigrateSpec(string(data))
+	if err != nil {
+		return err
+	}
+	if migrateStdout {
+		fmt.Print(migrated)
+		return nil
+	}
+	if version == util.LatestSpecVersion {
+		log.Info().Str("file", specFile).Msgf("Already at apiVersion %s", version)
+		return nil
+	}
+
+	if err := os.WriteFile(specFile, []byte(migrated), info.Mode().Perm()); err != nil {
+		return err
+	}
+	log.Info().Str("file", specFile).Msgf("Migrated from apiVersion %s to %s", version, util.LatestSpecVersion)
+	return nil
+}
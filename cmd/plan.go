@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/files"
+	"github.com/BESTSELLER/harpocrates/secrets"
+	"github.com/BESTSELLER/harpocrates/vault"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the files and keys that would be written, without writing them",
+	Long: `Show the files and keys that would be written, without writing them.
+
+The secrets are fetched from Vault like fetch does, and for every output file the format, owner and mode are printed
+along with each key, the secret and key it comes from, and the prefix and uppercase it is written with.
+The level of the spec a prefix or uppercase is set on is shown next to it, e.g. 'APP_ (secret)'.
+Values are masked, or shown as the start of their HMAC-SHA256 with --hash. To compare the hashes between runs,
+give the same key to every run with --hash-key or HARPOCRATES_HASH_KEY, otherwise a random key is used.
+
+  harpocrates plan -f secrets.yaml
+  HARPOCRATES_HASH_KEY=... harpocrates plan --hash --json -f secrets.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		skipWrites()
+
+		allSecrets, ok := extractSecrets(cmd, args)
+		if !ok {
+			return
+		}
+
+		if planHash && hashKey == "" && os.Getenv(hashKeyEnv) == "" {
+			log.Warn().Msgf("The values are hashed with a random key, use --hash-key or %s to compare them between runs", hashKeyEnv)
+		}
+
+		plan, err := planFiles(allSecrets)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to plan the files")
+		}
+
+		if planJSON {
+			err = printJSON(plan)
+		} else {
+			err = printPlanTable(plan)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to print the plan")
+		}
+	},
+}
+
+var (
+	planJSON bool
+	planHash bool
+)
+
+func init() {
+	planCmd.Flags().BoolVar(&planJSON, "json", false, "print the plan as json")
+	planCmd.Flags().BoolVar(&planHash, "hash", false, "show the start of the HMAC-SHA256 of each value instead of masking it")
+	planCmd.Flags().StringVar(&hashKey, "hash-key", "", "key to hash the values with, defaults to "+hashKeyEnv+" or a random key")
+
+	rootCmd.AddCommand(planCmd)
+}
+
+// plannedFile is a file that would be written
+type plannedFile struct {
+	File   string       `json:"file"`
+	Format string       `json:"format,omitempty"`
+	Owner  int          `json:"owner"`
+	Group  int          `json:"group"`
+	Mode   string       `json:"mode"`
+	Keys   []plannedKey `json:"keys"`
+}
+
+// plannedKey is a key that would be written, and where it comes from
+type plannedKey struct {
+	Key           string `json:"key"`
+	Path          string `json:"path"`
+	VaultKey      string `json:"vaultKey"`
+	Prefix        string `json:"prefix"`
+	PrefixFrom    string `json:"prefixFrom"`
+	UpperCase     bool   `json:"upperCase"`
+	UpperCaseFrom string `json:"upperCaseFrom"`
+	Value         string `json:"value"`
+	// Existing marks a key that is kept from the file the secrets are merged with
+	Existing bool `json:"existing,omitempty"`
+}
+
+// planFiles lists the files a fetch would write, with outputs sharing a file combined and merged with the existing file like a fetch does
+func planFiles(allSecrets []vault.Outputs) ([]plannedFile, error) {
+	pending, _, err := renderSecrets(allSecrets, readCurrentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []plannedFile
+	for _, file := range pending {
+		planned := newPlannedFile(file.name, file.options)
+		planned.Format = file.format
+		for _, key := range file.keys {
+			planned.Keys = append(planned.Keys, newPlannedKey(secrets.KeyName(file.format, key), file.result[key], file.sources[key]))
+		}
+		plan = append(plan, planned)
+	}
+	return plan, nil
+}
+
+func newPlannedFile(fileName string, options files.Options) plannedFile {
+	file := plannedFile{Owner: config.Config.Owner, Group: -1, Mode: fmt.Sprintf("%04o", files.DefaultMode)}
+	if options.Owner != nil {
+		file.Owner = *options.Owner
+	}
+	if options.Group != nil {
+		file.Group = *options.Group
+	}
+	if options.Mode != 0 {
+		file.Mode = fmt.Sprintf("%04o", options.Mode)
+	}
+
+	fileName = files.ResolveFileName(fileName, options)
+	if config.Config.Output == files.Stdout {
+		file.File = fileName
+	} else {
+		file.File = filepath.Join(config.Config.Output, fileName)
+	}
+	return file
+}
+
+func newPlannedKey(key string, value any, source secrets.Source) plannedKey {
+	return plannedKey{
+		Key:           key,
+		Path:          source.Path,
+		VaultKey:      source.Key,
+		Prefix:        source.Prefix,
+		PrefixFrom:    source.PrefixFrom,
+		UpperCase:     source.UpperCase,
+		UpperCaseFrom: source.UpperCaseFrom,
+		Value:         maskValue(value),
+		Existing:      source.Path == "",
+	}
+}
+
+// maskValue hides a value, or gives the start of its hash with --hash
+func maskValue(value any) string {
+	if !planHash {
+		return "****"
+	}
+	return hashValue(value)
+}
+
+// hashKeyEnv is the environment variable with the key values are hashed with, when --hash-key is not given
+const hashKeyEnv = "HARPOCRATES_HASH_KEY"
+
+// hashKey is the key values are hashed with, it is set by --hash-key or on the first hash
+var hashKey string
+
+// hashValue returns the start of the HMAC-SHA256 of a value as it is written, enough to tell values apart without showing them.
+// The key keeps short or guessable values from being found by hashing candidates.
+func hashValue(value any) string {
+	if hashKey == "" {
+		hashKey = os.Getenv(hashKeyEnv)
+	}
+	if hashKey == "" {
+		hashKey = rand.Text()
+	}
+	mac := hmac.New(sha256.New, []byte(hashKey))
+	mac.Write([]byte(secrets.PlainValue(value)))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// printPlanTable prints every file followed by a row per key, an unset owner or group is shown as a dash
+func printPlanTable(plan []plannedFile) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, file := range plan {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		format := file.Format
+		if format == "" {
+			format = "raw"
+		}
+		fmt.Fprintf(w, "%s (format: %s, owner: %s, group: %s, mode: %s)\n", file.File, format, planID(file.Owner), planID(file.Group), file.Mode)
+		fmt.Fprintln(w, "  KEY\tSECRET\tVAULT KEY\tPREFIX\tUPPERCASE\tVALUE")
+		for _, key := range file.Keys {
+			if key.Existing {
+				fmt.Fprintf(w, "  %s\t(existing file)\t-\t-\t-\t%s\n", key.Key, key.Value)
+				continue
+			}
+			prefix := "-"
+			if key.Prefix != "" {
+				prefix = fmt.Sprintf("%s (%s)", key.Prefix, key.PrefixFrom)
+			}
+			upperCase := fmt.Sprintf("%t (%s)", key.UpperCase, key.UpperCaseFrom)
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", key.Key, key.Path, key.VaultKey, prefix, upperCase, key.Value)
+		}
+	}
+	return w.Flush()
+}
+
+func planID(id int) string {
+	if id == -1 {
+		return "-"
+	}
+	return strconv.Itoa(id)
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/secrets"
+	"github.com/BESTSELLER/harpocrates/validate"
 	"github.com/gookit/color"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -47,14 +49,23 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&config.Config.VaultToken, "vault-token", "", "vault token in clear text")
 	rootCmd.PersistentFlags().BoolVar(&config.Config.GcpWorkloadID, "gcpWorkloadID", false, "Enable GcpWorkloadID auth method instead of using vault token")
 
-	rootCmd.PersistentFlags().StringVar(&config.Config.Format, "format", "", "output format, either json or env, defaults to env")
-	rootCmd.PersistentFlags().StringVar(&config.Config.Output, "output", "", "folder in which secret files will be created e.g. /path/to/folder")
+	rootCmd.PersistentFlags().StringVar(&config.Config.Format, "format", "", "output format, one of: "+strings.Join(secrets.Formats(), ", ")+", defaults to env")
+	rootCmd.PersistentFlags().StringVar(&config.Config.Template, "template", "", "path to a Go text/template file, used when format is template")
+	rootCmd.PersistentFlags().StringVar(&config.Config.Output, "output", "", "folder in which secret files will be created e.g. /path/to/folder, or - to print the secrets to stdout")
 	rootCmd.PersistentFlags().IntVar(&config.Config.Owner, "owner", -1, "UID of the owner that the secret files will be created e.g. 2")
+	rootCmd.PersistentFlags().StringVar(&config.Config.Profile, "profile", "", "profile of the spec to use e.g. dev, defaults to the defaultProfile of the spec")
+	rootCmd.PersistentFlags().BoolVar(&config.Config.StrictVariables, "strict-variables", false, "fail when a ${VAR} in the spec is not set in the environment and has no default")
 	rootCmd.PersistentFlags().StringVar(&config.Config.Prefix, "prefix", "", "key prefix e.g TEST_ will produce TEST_key=secret")
+	rootCmd.PersistentFlags().BoolVar(&config.Config.Nested, "nested", false, "will expand dotted keys and json values into nested objects for json, yaml and template")
 	rootCmd.PersistentFlags().BoolVar(&config.Config.UpperCase, "uppercase", false, "will convert key to UPPERCASE")
 	rootCmd.PersistentFlags().StringVar(&config.Config.LogLevel, "log-level", "", "LogLevel, default is info")
 	rootCmd.PersistentFlags().BoolVar(&config.Config.Validate, "validate", false, "Validate, will only validate the secrets file")
+	rootCmd.PersistentFlags().StringVar(&config.Config.ValidateFormat, "validate-format", "", "format of the --validate report, one of: "+strings.Join(validate.ReportFormats, ", ")+", defaults to text")
 	rootCmd.PersistentFlags().BoolVar(&config.Config.Append, "append", true, "Append, appends secrets to a file, defaults to true")
+	rootCmd.PersistentFlags().StringVar(&config.Config.Order, "order", "", "order to write the keys in, either sorted or spec, defaults to sorted")
+	rootCmd.PersistentFlags().StringVar(&config.Config.Duplicates, "duplicates", "", "what to do when two secrets give the same key in one output, either error, warn, first-wins or last-wins, defaults to warn")
+	rootCmd.PersistentFlags().StringVar(&config.Config.Conflict, "conflict", "", "what to do when appending a key that already exists with a different value, either error, first-wins or last-wins, defaults to last-wins")
+	rootCmd.PersistentFlags().BoolVar(&config.Config.SymlinkSwap, "symlink-swap", false, "write all files through a ..data symlink that is swapped at once, like Kubernetes projected volumes")
 	secret = rootCmd.PersistentFlags().StringSlice("secret", []string{}, "vault path to secret, supports array of secrets e.g. SECRETENGINE/data/test/dev,SECRETENGINE/data/test/prod")
 
 }
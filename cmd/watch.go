@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BESTSELLER/harpocrates/util"
+	"github.com/BESTSELLER/harpocrates/vault"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// specPollInterval is how often the spec file is checked for changes, which is cheap compared to asking Vault
+const specPollInterval = time.Second
+
+var (
+	watch         bool
+	watchInterval time.Duration
+	watchSignal   string
+	watchGrace    time.Duration
+)
+
+// runWatched runs the command like dev does, and restarts it with fresh secrets when the spec file, a spec it includes or a secret in Vault changes.
+// The command is stopped with --watch-signal and killed if it is still running after --watch-grace.
+// It returns when the command exits by itself, with the error of the command.
+func runWatched(cmd *cobra.Command, args []string, input util.SecretJSON, allSecrets []vault.Outputs, secretEnvs []string) error {
+	stopSignal, err := util.ParseSignal(watchSignal)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid --watch-signal")
+	}
+	if watchInterval <= 0 {
+		log.Fatal().Msg("--watch-interval must be greater than zero")
+	}
+
+	// The PTY session is shared, so the terminal is left alone when the command restarts
+	var session *util.PTYSession
+	var signals chan os.Signal
+	if !noPTY {
+		session = util.NewPTYSession(redact)
+		defer session.Close()
+
+		// Without a PTY signals are forwarded to the command by RunCmd
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+	}
+
+	w := newWatcher(specFiles(input), input.Paths())
+	changes := make(chan string)
+	go w.run(changes)
+
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		execCmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		execCmd.Env = childEnv(secretEnvs)
+		execCmd.Cancel = func() error {
+			return util.StopProcessGroup(execCmd.Process, stopSignal)
+		}
+		execCmd.WaitDelay = watchGrace
+
+		redactValues := secretValues(allSecrets)
+		done := make(chan error, 1)
+		go func() {
+			if session != nil {
+				done <- session.Run(execCmd, redactValues)
+			} else {
+				done <- util.RunCmd(execCmd, redactValues, redact)
+			}
+		}()
+
+		// Wait for a change that can be fetched and rendered, the command keeps running if either fails.
+		// Nothing is written until the command has stopped.
+		var pending []*pendingFile
+		for restart := false; !restart; {
+			select {
+			case err := <-done:
+				cancel()
+				return err
+			case <-signals:
+				cancel()
+				return <-done
+			case reason := <-changes:
+				log.Info().Msgf("%s changed, fetching secrets", reason)
+				newInput, newSecrets, ok, err := loadSecrets(cmd, args)
+				var newEnvs []string
+				if err == nil && ok {
+					// The output is a folder of its own that only holds what the previous run wrote,
+					// so nothing is merged with it and keys that are gone are removed from the files
+					pending, newEnvs, err = renderSecrets(newSecrets, noCurrentFiles)
+				}
+				if err != nil || !ok {
+					log.Error().Err(err).Msg("Unable to fetch the secrets, the command keeps running with the previous secrets")
+					continue
+				}
+				input, allSecrets, secretEnvs = newInput, newSecrets, newEnvs
+				restart = true
+			}
+		}
+
+		log.Info().Msgf("Restarting '%s'", args[0])
+		cancel()
+		<-done
+
+		writeFiles(pending)
+		w.setSources(specFiles(input), input.Paths())
+	}
+}
+
+// specFiles returns the spec file and the spec files it includes
+func specFiles(input util.SecretJSON) []string {
+	if secretFile == "" {
+		return input.IncludedFiles
+	}
+	return append([]string{secretFile}, input.IncludedFiles...)
+}
+
+// watcher polls the spec files and the versions of the secrets in Vault for changes
+type watcher struct {
+	mu        sync.Mutex
+	specFiles []string
+	paths     []string
+	versions  map[string]int
+}
+
+func newWatcher(specFiles []string, paths []string) *watcher {
+	w := &watcher{versions: map[string]int{}}
+	w.setSources(specFiles, paths)
+	return w
+}
+
+// setSources changes the spec files and paths to watch, the current state of new ones is what later states are compared with
+func (w *watcher) setSources(specFiles []string, paths []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.specFiles = specFiles
+	w.paths = paths
+}
+
+// run sends a description of every change it finds to changes, it never returns
+func (w *watcher) run(changes chan<- string) {
+	specStates := map[string]string{}
+	w.changedSpecFile(specStates) // Records the current states
+	specTicker := time.NewTicker(specPollInterval)
+	vaultTicker := time.NewTicker(watchInterval)
+	w.changedSecret() // Records the current versions
+
+	for {
+		select {
+		case <-specTicker.C:
+			if specFile := w.changedSpecFile(specStates); specFile != "" {
+				changes <- fmt.Sprintf("The spec file '%s'", specFile)
+			}
+		case <-vaultTicker.C:
+			if path := w.changedSecret(); path != "" {
+				changes <- fmt.Sprintf("The secret '%s'", path)
+			}
+		}
+	}
+}
+
+// changedSpecFile returns the first spec file that changed since its state in states, or an empty string if none changed
+func (w *watcher) changedSpecFile(states map[string]string) string {
+	w.mu.Lock()
+	specFiles := w.specFiles
+	w.mu.Unlock()
+
+	changed := ""
+	for _, specFile := range specFiles {
+		state := fileState(specFile)
+		previous, known := states[specFile]
+		states[specFile] = state
+		if known && previous != state && changed == "" {
+			changed = specFile
+		}
+	}
+	return changed
+}
+
+// changedSecret returns the first path with a new version in Vault, or an empty string if none changed
+func (w *watcher) changedSecret() string {
+	w.mu.Lock()
+	paths := w.paths
+	w.mu.Unlock()
+
+	vaultClient := vault.NewClient()
+	changed := ""
+	for _, path := range paths {
+		version, err := vaultClient.SecretVersion(path)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Unable to watch '%s' for changes", path)
+			continue
+		}
+		previous, known := w.versions[path]
+		w.versions[path] = version
+		if known && previous != version && changed == "" {
+			changed = path
+		}
+	}
+	return changed
+}
+
+// fileState is what changes when a file is saved, a missing file gives an empty state
+func fileState(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
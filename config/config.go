@@ -9,21 +9,30 @@ import (
 
 // GlobalConfig defines the structure of the global configuration parameters
 type GlobalConfig struct {
-	Append        bool   `required:"false"`
-	AuthName      string `required:"false"`
-	FileName      string `required:"false"`
-	Format        string `required:"false"`
-	LogLevel      string `required:"false"`
-	Output        string `required:"false"`
-	Owner         int    `required:"false"`
-	Prefix        string `required:"false"`
-	RoleName      string `required:"false"`
-	TokenPath     string `required:"false"`
-	UpperCase     bool   `required:"false"`
-	Validate      bool   `required:"false"`
-	VaultAddress  string `required:"false"`
-	VaultToken    string `required:"false"`
-	GcpWorkloadID bool   `required:"false"`
+	Append          bool   `required:"false"`
+	AuthName        string `required:"false"`
+	Conflict        string `required:"false"`
+	Duplicates      string `required:"false"`
+	FileName        string `required:"false"`
+	Format          string `required:"false"`
+	LogLevel        string `required:"false"`
+	Nested          bool   `required:"false"`
+	Order           string `required:"false"`
+	Output          string `required:"false"`
+	Owner           int    `required:"false"`
+	Prefix          string `required:"false"`
+	Profile         string `required:"false"`
+	RoleName        string `required:"false"`
+	StrictVariables bool   `required:"false"`
+	SymlinkSwap     bool   `required:"false"`
+	Template        string `required:"false"`
+	TokenPath       string `required:"false"`
+	UpperCase       bool   `required:"false"`
+	Validate        bool   `required:"false"`
+	ValidateFormat  string `required:"false"`
+	VaultAddress    string `required:"false"`
+	VaultToken      string `required:"false"`
+	GcpWorkloadID   bool   `required:"false"`
 }
 
 // Config stores the Global Configuration.
@@ -47,11 +56,17 @@ func SyncEnvToFlags(cmd *cobra.Command) {
 			(&Config).GcpWorkloadID = true
 		}
 	}
+	if !Config.StrictVariables {
+		if envVar, ok := os.LookupEnv("HARPOCRATES_STRICT_VARIABLES"); ok && strings.ToLower(envVar) == "true" {
+			Config.StrictVariables = true
+		}
+	}
 	tryEnv("format", &Config.Format, notRequired, cmd)
 	if Config.Format == "" {
 		Config.Format = "env"
 	}
 	tryEnv("log_level", &Config.LogLevel, notRequired, cmd)
+	tryEnv("HARPOCRATES_PROFILE", &Config.Profile, notRequired, cmd)
 	tryEnv("HARPOCRATES_FILENAME", &Config.FileName, notRequired, cmd)
 	if Config.FileName == "" {
 		Config.FileName = "secrets"
@@ -2,9 +2,11 @@ package files
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 
 	"github.com/BESTSELLER/harpocrates/config"
 	"github.com/rs/zerolog/log"
@@ -12,6 +14,31 @@ import (
 
 var fileNameRegexp = regexp.MustCompile("[^a-zA-Z0-9.-]+")
 
+// DefaultMode is the file mode used when no mode is given
+const DefaultMode os.FileMode = 0600
+
+// Stdout is the output that prints the content to standard output instead of writing files
+const Stdout = "-"
+
+// SkipWrites makes Write skip every file, used by commands that must not touch the disk
+var SkipWrites bool
+
+// written holds the paths written during this run. Write only appends to content written earlier in the run,
+// so a file left by an earlier run is replaced and running the same spec twice gives the same files.
+var written = map[string]bool{}
+
+// Options holds the optional settings for a written file
+type Options struct {
+	// Owner is the UID of the file owner, defaults to the --owner flag
+	Owner *int
+	// Group is the GID of the file group
+	Group *int
+	// Mode is the file mode, defaults to DefaultMode
+	Mode os.FileMode
+	// ExactName keeps the file name as given instead of replacing unsupported characters
+	ExactName bool
+}
+
 // Read will read the content of a file and return it as a string.
 func Read(filePath string) (string, error) {
 	data, err := os.ReadFile(filePath)
@@ -22,10 +49,43 @@ func Read(filePath string) (string, error) {
 	return fmt.Sprint(string(data)), nil
 }
 
-// Write will write some string data to a file
-func Write(output string, fileName string, content any, owner *int, append bool) {
-	fileName = fixFileName(fileName)
-	path := filepath.Join(output, fileName)
+// ParseMode parses an octal file mode such as "0644"
+func ParseMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return DefaultMode, nil
+	}
+	parsed, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || parsed > 0777 {
+		return 0, fmt.Errorf("invalid file mode '%s', expected an octal mode such as 0644", mode)
+	}
+	return os.FileMode(parsed), nil
+}
+
+// Write will write some data to a file.
+// Strings and byte slices are written as is, so binary content is kept byte-exact.
+//
+// With append the content is added to what an earlier Write of this run wrote to the file, a file from an earlier run is replaced.
+// The content is written to a temporary file which is then renamed, so readers never see a half-written file.
+// With the symlink swap layout the file is staged and only becomes visible when Commit is called.
+// When output is Stdout the content is printed instead.
+func Write(output string, fileName string, content any, options Options, append bool) {
+	fileName = ResolveFileName(fileName, options)
+
+	if SkipWrites {
+		log.Warn().Msgf("Skipped writing the file '%s', files are not written by this command", fileName)
+		return
+	}
+	if output == Stdout {
+		if err := writeContent(os.Stdout, content); err != nil {
+			log.Fatal().Err(err).Msg("Unable to write to stdout")
+		}
+		return
+	}
+
+	mode := options.Mode
+	if mode == 0 {
+		mode = DefaultMode
+	}
 
 	if _, err := os.Stat(output); os.IsNotExist(err) {
 		err = os.MkdirAll(output, 0700)
@@ -34,44 +94,137 @@ func Write(output string, fileName string, content any, owner *int, append bool)
 		}
 	}
 
-	overWriteOrAppend := os.O_TRUNC
-	if append {
-		overWriteOrAppend = os.O_APPEND
+	appendTo := currentPath(output, fileName)
+	dir := output
+	if config.Config.SymlinkSwap {
+		dir = stage(output, fileName)
 	}
+	path := filepath.Join(dir, fileName)
 
-	f, err := os.OpenFile(path, overWriteOrAppend|os.O_CREATE|os.O_WRONLY, 0600)
+	f, err := os.CreateTemp(dir, "."+fileName+".tmp-*")
 	if err != nil {
-		log.Fatal().Err(err).Msgf("An error happened while trying to open file %s", path)
+		log.Fatal().Err(err).Msgf("An error happened while trying to create a temporary file for %s", path)
 	}
+	// Removes the temporary file if we fail before it is renamed
+	defer os.Remove(f.Name()) //nolint:errcheck
 
-	defer func() {
-		if err := f.Close(); err != nil {
-			log.Fatal().Err(err).Msgf("Unable to close file '%s'", path)
+	writtenKey := filepath.Join(output, fileName)
+	if append && written[writtenKey] {
+		if err := copyExisting(f, appendTo); err != nil {
+			log.Fatal().Err(err).Msgf("Unable to read the existing content of '%s'", appendTo)
 		}
-	}()
+	}
 
-	if _, err = fmt.Fprintf(f, "%v", content); err != nil {
+	if err = writeContent(f, content); err != nil {
 		log.Fatal().Err(err).Msgf("Unable to write to file '%s'", path)
 	}
-	log.Debug().Msgf("Wrote file '%s'", path)
+
+	if err := f.Chmod(mode); err != nil {
+		log.Fatal().Err(err).Msgf("Unable to set mode on file '%s'", path)
+	}
 
 	// set permissions on file and folder
-	if owner != nil {
-		setPermissions(f, path, output, *owner)
-		return
+	owner := config.Config.Owner
+	if options.Owner != nil {
+		owner = *options.Owner
 	}
-	if config.Config.Owner != -1 {
-		setPermissions(f, path, output, config.Config.Owner)
-		return
+	group := -1
+	if options.Group != nil {
+		group = *options.Group
+	}
+	if owner != -1 || group != -1 {
+		dirs := []string{output}
+		if dir != output {
+			dirs = []string{output, dir}
+		}
+		setPermissions(f, path, owner, group, dirs...)
+	}
+
+	if err := f.Sync(); err != nil {
+		log.Fatal().Err(err).Msgf("Unable to sync file '%s'", path)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal().Err(err).Msgf("Unable to close file '%s'", path)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		log.Fatal().Err(err).Msgf("Unable to move the temporary file to '%s'", path)
 	}
+	if err := syncDir(dir); err != nil {
+		log.Fatal().Err(err).Msgf("Unable to sync dir '%s'", dir)
+	}
+	written[writtenKey] = true
+	log.Debug().Msgf("Wrote file '%s'", path)
+}
+
+// ReadCurrent returns the content a file written by Write has right now, including files staged for the symlink swap layout.
+// The returned bool is false if the file doesn't exist yet.
+func ReadCurrent(output string, fileName string, options Options) (string, bool, error) {
+	path := currentPath(output, ResolveFileName(fileName, options))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("unable to read the file at path '%s': %w", path, err)
+	}
+	return string(data), true, nil
 }
 
-func setPermissions(f *os.File, path string, output string, owner int) {
-	if err := os.Chown(output, owner, -1); err != nil {
-		log.Fatal().Err(err).Msgf("Unable to set permissions to folder '%s'", output)
+// ResolveFileName returns the name a file is written as, such as the name a plan lists
+func ResolveFileName(fileName string, options Options) string {
+	if !options.ExactName {
+		return fixFileName(fileName)
+	}
+	if fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." {
+		log.Fatal().Msgf("The file name '%s' must not contain a path", fileName)
+	}
+	return fileName
+}
+
+// currentPath returns where the current content of a file is, a file staged in this run takes precedence
+func currentPath(output string, fileName string) string {
+	if stagedDir, ok := staged[output]; ok && stagedDir.files[fileName] {
+		return filepath.Join(stagedDir.dir, fileName)
+	}
+	return filepath.Join(output, fileName)
+}
+
+// copyExisting copies the content of the file at path to w, a missing file is treated as empty
+func copyExisting(w io.Writer, path string) error {
+	existing, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer existing.Close() //nolint:errcheck // We only read from the file
+
+	_, err = io.Copy(w, existing)
+	return err
+}
+
+func writeContent(w io.Writer, content any) error {
+	var err error
+	switch c := content.(type) {
+	case []byte:
+		_, err = w.Write(c)
+	case string:
+		_, err = io.WriteString(w, c)
+	default:
+		_, err = fmt.Fprintf(w, "%v", c)
+	}
+	return err
+}
+
+func setPermissions(f *os.File, path string, owner int, group int, dirs ...string) {
+	for _, dir := range dirs {
+		if err := os.Chown(dir, owner, group); err != nil {
+			log.Fatal().Err(err).Msgf("Unable to set permissions to folder '%s'", dir)
+		}
 	}
 
-	if err := f.Chown(owner, -1); err != nil {
+	if err := f.Chown(owner, group); err != nil {
 		log.Fatal().Err(err).Msgf("Unable to set permissions to file '%s'", path)
 	}
 }
@@ -0,0 +1,141 @@
+package files
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BESTSELLER/harpocrates/config"
+)
+
+func init() {
+	// Don't change the owner of the test files
+	config.Config.Owner = -1
+}
+
+func TestWriteBinaryWithMode(t *testing.T) {
+	output := t.TempDir()
+	content := []byte{0xfe, 0xed, 0xfe, 0xed, 0x00, 0x02}
+
+	Write(output, "keystore.jks", content, Options{Mode: 0644, ExactName: true}, false)
+
+	path := filepath.Join(output, "keystore.jks")
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if !bytes.Equal(written, content) {
+		t.Errorf("expected %v, got %v", content, written)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("expected mode 0644, got %o", info.Mode().Perm())
+	}
+}
+
+func TestWriteFixesFileName(t *testing.T) {
+	output := t.TempDir()
+
+	Write(output, "TEST_key 1", "value", Options{}, false)
+
+	info, err := os.Stat(filepath.Join(output, "TEST_key_1"))
+	if err != nil {
+		t.Fatalf("could not stat file: %v", err)
+	}
+	if info.Mode().Perm() != DefaultMode {
+		t.Errorf("expected mode %o, got %o", DefaultMode, info.Mode().Perm())
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected os.FileMode
+		wantErr  bool
+	}{
+		{"", DefaultMode, false},
+		{"0644", 0644, false},
+		{"440", 0440, false},
+		{"0999", 0, true},
+		{"rw-r--r--", 0, true},
+		{"01777", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			mode, err := ParseMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if mode != tt.expected {
+				t.Errorf("expected %o, got %o", tt.expected, mode)
+			}
+		})
+	}
+}
+
+func TestWriteAppend(t *testing.T) {
+	output := t.TempDir()
+
+	Write(output, "secrets", "export A='1'\n", Options{}, true)
+	Write(output, "secrets", "export B='2'\n", Options{}, true)
+
+	content, err := os.ReadFile(filepath.Join(output, "secrets"))
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	expected := "export A='1'\nexport B='2'\n"
+	if string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, string(content))
+	}
+
+	entries, _ := os.ReadDir(output)
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left, got %v", entries)
+	}
+}
+
+func TestWriteAppendIsIdempotent(t *testing.T) {
+	output := t.TempDir()
+	run := func() []byte {
+		Write(output, "secrets.toml", "A = \"1\"\n", Options{}, true)
+		Write(output, "secrets.toml", "B = \"2\"\n", Options{}, true)
+		Write(output, "keystore.jks", []byte{0xfe, 0xed, 0xfe, 0xed}, Options{}, true)
+		Commit()
+
+		var content []byte
+		for _, name := range []string{"secrets.toml", "keystore.jks"} {
+			data, err := os.ReadFile(filepath.Join(output, name))
+			if err != nil {
+				t.Fatalf("could not read file: %v", err)
+			}
+			content = append(content, data...)
+		}
+		return content
+	}
+
+	first := run()
+	if second := run(); !bytes.Equal(first, second) {
+		t.Errorf("expected the second run to give the same files, got %q and %q", first, second)
+	}
+	if expected := "A = \"1\"\nB = \"2\"\n\xfe\xed\xfe\xed"; string(first) != expected {
+		t.Errorf("expected %q, got %q", expected, string(first))
+	}
+}
+
+func TestWriteSkipWrites(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "secrets")
+	SkipWrites = true
+	t.Cleanup(func() { SkipWrites = false })
+
+	Write(output, "secrets.env", "value", Options{}, false)
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written to %s, got %v", output, err)
+	}
+}
@@ -0,0 +1,142 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// dataDirName is the symlink pointing at the current set of files, like in Kubernetes projected volumes
+const dataDirName = "..data"
+
+// stagedOutput holds the files written to an output folder during this run
+type stagedOutput struct {
+	dir   string
+	files map[string]bool
+}
+
+// staged is keyed by output folder
+var staged = map[string]*stagedOutput{}
+
+// stage returns the staging folder for output and records fileName as part of the new set of files.
+//
+// The layout of the output folder is:
+//
+//	output/..2026_01_02_15_04_05.000000000  the files of a single run
+//	output/..data -> ..2026_01_02_15_04_05.000000000
+//	output/secrets -> ..data/secrets
+func stage(output string, fileName string) string {
+	stagedDir, ok := staged[output]
+	if !ok {
+		dir, err := os.MkdirTemp(output, time.Now().UTC().Format("..2006_01_02_15_04_05.000000000"))
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Unable to create staging dir in '%s'", output)
+		}
+		stagedDir = &stagedOutput{dir: dir, files: map[string]bool{}}
+		staged[output] = stagedDir
+	}
+	stagedDir.files[fileName] = true
+	return stagedDir.dir
+}
+
+// Commit makes the files written with the symlink swap layout visible.
+// All files of an output folder change at once, as readers go through the ..data symlink which is swapped with a rename.
+// Commit does nothing when no files have been staged. It ends the run, so the next Write replaces the files instead of appending to them.
+func Commit() {
+	clear(written)
+	for output, stagedDir := range staged {
+		if err := commit(output, stagedDir); err != nil {
+			log.Fatal().Err(err).Msgf("Unable to swap in the new files in '%s'", output)
+		}
+		delete(staged, output)
+	}
+}
+
+func commit(output string, stagedDir *stagedOutput) error {
+	dataDir := filepath.Join(output, dataDirName)
+	oldTarget, err := os.Readlink(dataDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read the %s symlink: %w", dataDirName, err)
+	}
+
+	if err := syncDir(stagedDir.dir); err != nil {
+		return err
+	}
+
+	// Swap the ..data symlink, a rename is atomic so readers see either the old or the new set of files
+	tmpLink := dataDir + "_tmp"
+	if err := os.Remove(tmpLink); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(filepath.Base(stagedDir.dir), tmpLink); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpLink, dataDir); err != nil {
+		return err
+	}
+
+	// Point the visible files at ..data, they only have to be created once
+	for fileName := range stagedDir.files {
+		if err := linkFile(output, fileName); err != nil {
+			return err
+		}
+	}
+
+	if err := syncDir(output); err != nil {
+		return err
+	}
+
+	if oldTarget != "" && oldTarget != filepath.Base(stagedDir.dir) {
+		if err := os.RemoveAll(filepath.Join(output, oldTarget)); err != nil {
+			return fmt.Errorf("unable to remove the previous files: %w", err)
+		}
+	}
+
+	return removeStaleLinks(output, stagedDir.files)
+}
+
+// linkFile makes output/fileName a symlink to ..data/fileName, replacing a file written without the symlink swap layout
+func linkFile(output string, fileName string) error {
+	link := filepath.Join(output, fileName)
+	target := filepath.Join(dataDirName, fileName)
+
+	if current, err := os.Readlink(link); err == nil && current == target {
+		return nil
+	}
+
+	tmpLink := link + ".tmp-link"
+	if err := os.Remove(tmpLink); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(target, tmpLink); err != nil {
+		return err
+	}
+	return os.Rename(tmpLink, link)
+}
+
+// removeStaleLinks removes the symlinks of files that are no longer part of the set
+func removeStaleLinks(output string, current map[string]bool) error {
+	entries, err := os.ReadDir(output)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink == 0 || current[entry.Name()] {
+			continue
+		}
+		link := filepath.Join(output, entry.Name())
+		target, err := os.Readlink(link)
+		if err != nil || !strings.HasPrefix(target, dataDirName+string(filepath.Separator)) {
+			continue
+		}
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
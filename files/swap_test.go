@@ -0,0 +1,52 @@
+//go:build !windows
+
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BESTSELLER/harpocrates/config"
+)
+
+func TestWriteSymlinkSwap(t *testing.T) {
+	config.Config.SymlinkSwap = true
+	t.Cleanup(func() {
+		config.Config.SymlinkSwap = false
+	})
+	output := t.TempDir()
+
+	// first run
+	Write(output, "secrets", "first", Options{}, false)
+	Write(output, "old.json", "{}", Options{}, false)
+	if _, err := os.Stat(filepath.Join(output, "secrets")); !os.IsNotExist(err) {
+		t.Fatalf("expected the file to be hidden until commit, got %v", err)
+	}
+	Commit()
+	firstTarget, err := os.Readlink(filepath.Join(output, dataDirName))
+	if err != nil {
+		t.Fatalf("expected %s to be a symlink: %v", dataDirName, err)
+	}
+
+	// second run
+	Write(output, "secrets", "second", Options{}, false)
+	Commit()
+
+	content, err := os.ReadFile(filepath.Join(output, "secrets"))
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if string(content) != "second" {
+		t.Errorf("expected %q, got %q", "second", string(content))
+	}
+	if target, _ := os.Readlink(filepath.Join(output, "secrets")); target != filepath.Join(dataDirName, "secrets") {
+		t.Errorf("expected secrets to link to %s, got %q", dataDirName, target)
+	}
+	if _, err := os.Lstat(filepath.Join(output, "old.json")); !os.IsNotExist(err) {
+		t.Errorf("expected the link of a file that is no longer written to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(output, firstTarget)); !os.IsNotExist(err) {
+		t.Errorf("expected the previous files to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package files
+
+import (
+	"os"
+)
+
+// syncDir flushes the directory entry, so a rename survives a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close() //nolint:errcheck // We only care about the result of Sync
+
+	return d.Sync()
+}
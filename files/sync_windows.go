@@ -0,0 +1,9 @@
+//go:build windows
+
+package files
+
+func syncDir(dir string) error {
+	// Windows does not support syncing a directory handle.
+	// Renames are flushed by the file system itself.
+	return nil
+}
@@ -1,10 +1,14 @@
 package lsp
 
 import (
+	"os"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/BESTSELLER/harpocrates/util"
+	"github.com/BESTSELLER/harpocrates/vault"
 	"github.com/rs/zerolog/log"
 )
 
@@ -18,6 +22,8 @@ const (
 	ContextRoot
 	ContextSecretObject
 	ContextKeyObject
+	ContextProfileObject
+	ContextIncludeList
 )
 
 type CompletionProvider struct {
@@ -55,12 +61,22 @@ func (p *CompletionProvider) Provide(params CompletionParams) CompletionList {
 		return emptyList()
 	}
 
+	// Variables are completed wherever ${ is left open
+	if idx := strings.LastIndex(request.prefix, "${"); idx != -1 && !strings.Contains(request.prefix[idx:], "}") {
+		return p.completeVariables(request, request.prefix[idx+2:])
+	}
+
 	parsedCtx := parseContext(request.lines, params.Position.Line)
 
 	if request.fieldName != "" {
 		switch parsedCtx.Type {
 		case ContextRoot:
+			if request.fieldName == "defaultProfile" {
+				return p.completeValue(request, parsedCtx, map[string][]string{"defaultProfile": p.specNames(request, profileNames)})
+			}
 			return p.completeValue(request, parsedCtx, GetRootFieldVals())
+		case ContextProfileObject:
+			return p.completeValue(request, parsedCtx, GetProfileFieldVals())
 		case ContextSecretObject, ContextSecretsList:
 			return p.completeValue(request, parsedCtx, GetSecretFieldVals())
 		case ContextKeyObject, ContextKeysList:
@@ -80,6 +96,10 @@ func (p *CompletionProvider) Provide(params CompletionParams) CompletionList {
 		return p.completeSecretObject(request, parsedCtx)
 	case ContextKeyObject:
 		return p.completeKeyObject(request, parsedCtx)
+	case ContextProfileObject:
+		return p.completeProfileObject(request, parsedCtx)
+	case ContextIncludeList:
+		return p.completeIncludes(request, parsedCtx)
 	default:
 		return emptyList()
 	}
@@ -226,6 +246,69 @@ func (p *CompletionProvider) completeKeyObject(request completionRequest, parsed
 	return p.completeSchemaFields(request, parsedCtx, keyFields, GetKeyFieldDescription)
 }
 
+func (p *CompletionProvider) completeProfileObject(request completionRequest, parsedCtx ParserContext) CompletionList {
+	return p.completeSchemaFields(request, parsedCtx, profileFields, GetProfileFieldDescription)
+}
+
+func (p *CompletionProvider) completeVariables(request completionRequest, currentWord string) CompletionList {
+	var items []CompletionItem
+	for _, variable := range p.specNames(request, variableNames) {
+		if !strings.HasPrefix(variable, currentWord) {
+			continue
+		}
+		items = append(items, newVariableCompletionItem(variable, request, currentWord))
+	}
+	return CompletionList{Items: items}
+}
+
+// specNames returns the names found by namesFunc in the document and the specs it includes, sorted and without duplicates
+func (p *CompletionProvider) specNames(request completionRequest, namesFunc func([]string) []string) []string {
+	names := namesFunc(request.lines)
+	for _, lines := range includedSpecLines(uriPath(request.params.TextDocument.URI), request.lines) {
+		names = append(names, namesFunc(lines)...)
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+func (p *CompletionProvider) completeIncludes(request completionRequest, parsedCtx ParserContext) CompletionList {
+	specFile := uriPath(request.params.TextDocument.URI)
+	if specFile == "" {
+		return emptyList()
+	}
+
+	basePath := ""
+	if idx := strings.LastIndex(request.trimmedPrefix, "/"); idx != -1 {
+		basePath = request.trimmedPrefix[:idx+1]
+	}
+	entries, err := os.ReadDir(util.IncludePath(specFile, basePath))
+	if err != nil {
+		return emptyList()
+	}
+	currentWord := strings.TrimPrefix(request.trimmedPrefix, basePath)
+
+	var items []CompletionItem
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, currentWord) || parsedCtx.Existing[basePath+name] {
+			continue
+		}
+
+		if entry.IsDir() {
+			cmd := &Command{
+				Title:   "Trigger Suggest",
+				Command: "editor.action.triggerSuggest",
+			}
+			items = append(items, newCompletionItem(name+"/", CompletionItemKindFolder, request, currentWord, cmd))
+			continue
+		}
+		if isSpecFile(name) && util.IncludePath(specFile, basePath+name) != specFile {
+			items = append(items, newCompletionItem(name, CompletionItemKindFile, request, currentWord, nil))
+		}
+	}
+	return CompletionList{Items: items}
+}
+
 func (p *CompletionProvider) completeSchemaFields(request completionRequest, parsedCtx ParserContext, fields []string, descFunc func(string) string) CompletionList {
 	var items []CompletionItem
 	for _, field := range fields {
@@ -256,50 +339,20 @@ func (p *CompletionProvider) completeValue(request completionRequest, _ ParserCo
 }
 
 func (p *CompletionProvider) listSecretTokens(basePath string) []string {
-	queryPath := strings.Replace(basePath, "/data/", "/metadata/", 1)
-	cacheKey := "list:" + queryPath
+	cacheKey := "list:" + strings.Replace(basePath, "/data/", "/metadata/", 1)
 	if tokens, ok := p.secretListCache.Get(cacheKey); ok {
 		return tokens
 	}
 
-	if basePath == "" {
-		engines, err := p.vaultClient.ListSecretEngines()
-		if err != nil {
-			log.Error().Err(err).Msg("ListSecretEngines failed")
-			return nil
-		}
-		p.secretListCache.Set(cacheKey, engines)
-		return engines
-	}
-
-	tokens, err := p.vaultClient.ListKeys(queryPath)
+	tokens, err := vault.ListPath(p.vaultClient, basePath)
 	if err != nil {
-		log.Error().Err(err).Str("path", queryPath).Msg("ListKeys failed")
+		log.Error().Err(err).Str("path", basePath).Msg("Listing the path failed")
 		return nil
 	}
-	tokens = p.withEngineSubPath(tokens, basePath)
 	p.secretListCache.Set(cacheKey, tokens)
 	return tokens
 }
 
-func (p *CompletionProvider) withEngineSubPath(tokens []string, basePath string) []string {
-	subPath, err := p.vaultClient.GetEngineSubPath(basePath)
-	if err != nil {
-		log.Error().Err(err).Str("path", basePath).Msg("GetEngineSubPath failed")
-		return tokens
-	}
-	if subPath == "" {
-		return tokens
-	}
-
-	for _, token := range tokens {
-		if basePath+token == subPath {
-			return tokens
-		}
-	}
-	return append(tokens, strings.TrimPrefix(subPath, basePath))
-}
-
 func (p *CompletionProvider) readSecret(path string) (map[string]any, bool) {
 	cacheKey := "read:" + path
 	if secretData, ok := p.secretReadCache.Get(cacheKey); ok {
@@ -403,3 +456,25 @@ func newValueCompletionItem(label string, request completionRequest, currentWord
 		Command: nil,
 	}
 }
+
+func newVariableCompletionItem(label string, request completionRequest, currentWord string) CompletionItem {
+	insertText := label + "}"
+	wordStart := request.params.Position.Character - len(currentWord)
+
+	return CompletionItem{
+		Label:      label,
+		Kind:       CompletionItemKindVariable,
+		InsertText: insertText,
+		FilterText: insertText,
+		TextEdit: &TextEdit{
+			Range: Range{
+				Start: Position{
+					Line:      request.params.Position.Line,
+					Character: wordStart,
+				},
+				End: request.params.Position,
+			},
+			NewText: insertText,
+		},
+	}
+}
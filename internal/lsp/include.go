@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BESTSELLER/harpocrates/util"
+)
+
+// uriPath returns the file path of a file:// URI, or an empty string for other URIs
+func uriPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return ""
+	}
+	return parsed.Path
+}
+
+// includeEntries returns the specs listed in the root include block
+func includeEntries(lines []string) []string {
+	for i, line := range lines {
+		if getIndentCount(line) == 0 && extractKeyFromLine(line) == "include" {
+			return listValues(lines, i)
+		}
+	}
+	return nil
+}
+
+// listValues returns the list items directly below the block at blockIdx
+func listValues(lines []string, blockIdx int) []string {
+	blockIndent := getIndentCount(lines[blockIdx])
+	var values []string
+	for i := blockIdx + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if getIndentCount(lines[i]) <= blockIndent && !strings.HasPrefix(trimmed, "-") {
+			break
+		}
+		if strings.HasPrefix(trimmed, "-") {
+			if value := extractValFromList(trimmed); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// includedSpecLines returns the lines of every spec the spec at specFile includes, following their includes
+func includedSpecLines(specFile string, lines []string) [][]string {
+	var included [][]string
+	var follow func(specFile string, lines []string, chain []string)
+	follow = func(specFile string, lines []string, chain []string) {
+		for _, include := range includeEntries(lines) {
+			path := util.IncludePath(specFile, include)
+			includeChain, err := util.IncludeChain(chain, path)
+			if err != nil {
+				continue // A cycle, the spec is already followed
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			includedLines := strings.Split(string(data), "\n")
+			included = append(included, includedLines)
+			follow(path, includedLines, includeChain)
+		}
+	}
+
+	if specFile == "" {
+		return nil
+	}
+	chain, err := util.IncludeChain(nil, specFile)
+	if err != nil {
+		return nil
+	}
+	follow(specFile, lines, chain)
+	return included
+}
+
+// isSpecFile tells if the name has the extension of a spec file
+func isSpecFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
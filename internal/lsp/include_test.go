@@ -0,0 +1,45 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncludedSpecLines(t *testing.T) {
+	dir := t.TempDir()
+	specs := map[string]string{
+		"app.yaml":             "include:\n  - shared/platform.yaml\nsecrets:\n  - app/data/config\n",
+		"shared/platform.yaml": "include:\n  - ../app.yaml\n  - sentry.yaml\nprofiles:\n  prod:\n    prefix: PROD_\n",
+		"shared/sentry.yaml":   "profiles:\n  dev:\n    variables:\n      team: platform\n",
+	}
+	for name, content := range specs {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	specFile := filepath.Join(dir, "app.yaml")
+	if got := uriPath("file://" + specFile); got != specFile {
+		t.Fatalf("uriPath() = %q, want %q", got, specFile)
+	}
+
+	// The include of app.yaml is a cycle, which is not followed
+	included := includedSpecLines(specFile, strings.Split(specs["app.yaml"], "\n"))
+	if len(included) != 2 {
+		t.Fatalf("expected the 2 included specs, got %d", len(included))
+	}
+	var names []string
+	for _, lines := range included {
+		names = append(names, profileNames(lines)...)
+	}
+	if want := []string{"prod", "dev"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("profile names of the included specs = %v, want %v", names, want)
+	}
+}
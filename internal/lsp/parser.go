@@ -1,6 +1,7 @@
 package lsp
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -67,6 +68,14 @@ func parseContext(lines []string, targetLine int) ParserContext {
 				break
 			}
 
+			if strings.HasPrefix(trimmedLine, "include:") && indent == 0 {
+				result.Type = ContextIncludeList
+				blockLineIdx = i
+				blockIndent = indent
+				blockType = "include:"
+				break
+			}
+
 			if strings.HasPrefix(trimmedLine, "keys:") {
 				result.Type = ContextKeysList
 				blockLineIdx = i
@@ -117,8 +126,14 @@ func parseContext(lines []string, targetLine int) ParserContext {
 				break
 			}
 
-			// If we hit any other block we are inside that block
+			// If we hit any other block we are inside that block, which is only completed for a profile
 			if strings.Contains(trimmedLine, ":") {
+				if isProfileEntry(lines, i, indent) {
+					result.Type = ContextProfileObject
+					blockLineIdx = i
+					blockIndent = indent
+					blockType = "object:"
+				}
 				break
 			}
 
@@ -149,7 +164,7 @@ func parseContext(lines []string, targetLine int) ParserContext {
 		trimmed := strings.TrimSpace(line)
 
 		switch blockType {
-		case "secrets:", "keys:":
+		case "secrets:", "keys:", "include:":
 			if strings.HasPrefix(trimmed, "-") {
 				val := extractValFromList(trimmed)
 				if val != "" {
@@ -170,6 +185,73 @@ func parseContext(lines []string, targetLine int) ParserContext {
 	return result
 }
 
+// isProfileEntry tells if the line at lineIdx is the name of a profile in the root profiles block
+func isProfileEntry(lines []string, lineIdx int, indent int) bool {
+	for i := lineIdx - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if parentIndent := getIndentCount(lines[i]); parentIndent < indent {
+			return parentIndent == 0 && extractKeyFromLine(lines[i]) == "profiles"
+		}
+	}
+	return false
+}
+
+// profileNames returns the names of the profiles in the root profiles block
+func profileNames(lines []string) []string {
+	for i, line := range lines {
+		if getIndentCount(line) == 0 && extractKeyFromLine(line) == "profiles" {
+			return childKeys(lines, i)
+		}
+	}
+	return nil
+}
+
+// variableNames returns the variables that can be used in secret paths, which are the ones of every profile and the profile name
+func variableNames(lines []string) []string {
+	names := map[string]bool{"profile": true}
+	for i, line := range lines {
+		if extractKeyFromLine(line) == "variables" && getIndentCount(line) > 0 {
+			for _, name := range childKeys(lines, i) {
+				names[name] = true
+			}
+		}
+	}
+
+	variables := make([]string, 0, len(names))
+	for name := range names {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+// childKeys returns the keys directly below the block at blockIdx
+func childKeys(lines []string, blockIdx int) []string {
+	blockIndent := getIndentCount(lines[blockIdx])
+	childIndent := -1
+	var keys []string
+	for i := blockIdx + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		indent := getIndentCount(lines[i])
+		if indent <= blockIndent {
+			break
+		}
+		if childIndent == -1 {
+			childIndent = indent
+		}
+		if indent == childIndent {
+			if key := extractKeyFromLine(lines[i]); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
 func getIndentCount(line string) int {
 	count := 0
 	for _, ch := range line {
@@ -79,6 +79,43 @@ func TestParseContext(t *testing.T) {
 			wantParentSecret: "app/data/config",
 			wantExisting:     map[string]bool{},
 		},
+		{
+			name: "profile object",
+			document: strings.Join([]string{
+				"profiles:",
+				"  dev:",
+				"    prefix: DEV_",
+				"    vari",
+			}, "\n"),
+			targetLine:   3,
+			wantType:     ContextProfileObject,
+			wantExisting: map[string]bool{"prefix": true},
+		},
+		{
+			name: "profile variables",
+			document: strings.Join([]string{
+				"profiles:",
+				"  dev:",
+				"    variables:",
+				"      te",
+			}, "\n"),
+			targetLine:   3,
+			wantType:     ContextUnknown,
+			wantExisting: map[string]bool{},
+		},
+		{
+			name: "include list item",
+			document: strings.Join([]string{
+				"include:",
+				"  - shared/platform.yaml",
+				"  - shared/",
+				"secrets:",
+				"  - app/data/config",
+			}, "\n"),
+			targetLine:   2,
+			wantType:     ContextIncludeList,
+			wantExisting: map[string]bool{"shared/platform.yaml": true},
+		},
 		{
 			name:         "out of range",
 			document:     "secrets:\n  - app/data/config",
@@ -104,6 +141,29 @@ func TestParseContext(t *testing.T) {
 	}
 }
 
+func TestProfileAndVariableNames(t *testing.T) {
+	lines := strings.Split(strings.Join([]string{
+		"defaultProfile: dev",
+		"profiles:",
+		"  dev:",
+		"    variables:",
+		"      team: platform",
+		"  prod:",
+		"    prefix: PROD_",
+		"    variables:",
+		"      region: europe-west1",
+		"secrets:",
+		"  - secret/data/${team}/${profile}",
+	}, "\n"), "\n")
+
+	if got, want := profileNames(lines), []string{"dev", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("profileNames() = %v, want %v", got, want)
+	}
+	if got, want := variableNames(lines), []string{"profile", "region", "team"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("variableNames() = %v, want %v", got, want)
+	}
+}
+
 func TestGetIndentCount(t *testing.T) {
 	tests := map[string]int{
 		"no-indent": 0,
@@ -117,12 +117,14 @@ type CompletionList struct {
 }
 
 const (
-	CompletionItemKindText    = 1
-	CompletionItemKindMethod  = 2
-	CompletionItemKindField   = 5
-	CompletionItemKindValue   = 12
-	CompletionItemKindKeyword = 14
-	CompletionItemKindFolder  = 19
+	CompletionItemKindText     = 1
+	CompletionItemKindMethod   = 2
+	CompletionItemKindField    = 5
+	CompletionItemKindVariable = 6
+	CompletionItemKindValue    = 12
+	CompletionItemKindKeyword  = 14
+	CompletionItemKindFile     = 17
+	CompletionItemKindFolder   = 19
 )
 
 type Range struct {
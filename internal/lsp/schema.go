@@ -3,20 +3,24 @@ package lsp
 import (
 	"encoding/json"
 	"sort"
+	"strings"
 
 	"github.com/BESTSELLER/harpocrates/validate"
 )
 
 var (
-	rootFields      []string
-	secretFields    []string
-	keyFields       []string
-	rootFieldVals   map[string][]string
-	secretFieldVals map[string][]string
-	keyFieldVals    map[string][]string
-	rootFieldDesc   map[string]string
-	secretFieldDesc map[string]string
-	keyFieldDesc    map[string]string
+	rootFields       []string
+	secretFields     []string
+	keyFields        []string
+	profileFields    []string
+	rootFieldVals    map[string][]string
+	secretFieldVals  map[string][]string
+	keyFieldVals     map[string][]string
+	profileFieldVals map[string][]string
+	rootFieldDesc    map[string]string
+	secretFieldDesc  map[string]string
+	keyFieldDesc     map[string]string
+	profileFieldDesc map[string]string
 )
 
 func init() {
@@ -36,16 +40,27 @@ func parseSchema() {
 	rootFieldDesc = make(map[string]string)
 	secretFieldDesc = make(map[string]string)
 	keyFieldDesc = make(map[string]string)
+	profileFieldVals = make(map[string][]string)
+	profileFieldDesc = make(map[string]string)
+
+	defs, _ := schemaRoot["$defs"].(map[string]any)
 
 	// 1. Root fields
 	if props, ok := schemaRoot["properties"].(map[string]any); ok {
 		rootFields = extractKeys(props)
-		extractFieldValues(props, rootFieldVals)
-		extractFieldDesc(props, rootFieldDesc)
+		extractFieldValues(props, defs, rootFieldVals)
+		extractFieldDesc(props, defs, rootFieldDesc)
+	}
+
+	// Profile fields
+	profile, _ := defs["profile"].(map[string]any)
+	if profileProps, ok := profile["properties"].(map[string]any); ok {
+		profileFields = extractKeys(profileProps)
+		extractFieldValues(profileProps, defs, profileFieldVals)
+		extractFieldDesc(profileProps, defs, profileFieldDesc)
 	}
 
 	// Navigate to patternProperties of the secretsObject
-	defs, _ := schemaRoot["$defs"].(map[string]any)
 	secretsObj, _ := defs["secretsObject"].(map[string]any)
 	patternProps, _ := secretsObj["patternProperties"].(map[string]any)
 
@@ -59,8 +74,8 @@ func parseSchema() {
 	// 2. Secret fields
 	if secProps, ok := secretPattern["properties"].(map[string]any); ok {
 		secretFields = extractKeys(secProps)
-		extractFieldValues(secProps, secretFieldVals)
-		extractFieldDesc(secProps, secretFieldDesc)
+		extractFieldValues(secProps, defs, secretFieldVals)
+		extractFieldDesc(secProps, defs, secretFieldDesc)
 
 		// Navigate to key fields
 		if keysProp, ok := secProps["keys"].(map[string]any); ok {
@@ -79,8 +94,8 @@ func parseSchema() {
 				// 3. Key fields
 				if keyProps, ok := keyPattern["properties"].(map[string]any); ok {
 					keyFields = extractKeys(keyProps)
-					extractFieldValues(keyProps, keyFieldVals)
-					extractFieldDesc(keyProps, keyFieldDesc)
+					extractFieldValues(keyProps, defs, keyFieldVals)
+					extractFieldDesc(keyProps, defs, keyFieldDesc)
 				}
 			}
 		}
@@ -96,9 +111,22 @@ func extractKeys(m map[string]any) []string {
 	return keys
 }
 
-func extractFieldValues(props map[string]any, vals map[string][]string) {
+// resolveRef returns the definition a "$ref": "#/$defs/..." points to, or the field itself if it is not a reference.
+func resolveRef(fieldObj map[string]any, defs map[string]any) map[string]any {
+	ref, ok := fieldObj["$ref"].(string)
+	if !ok {
+		return fieldObj
+	}
+	if def, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any); ok {
+		return def
+	}
+	return fieldObj
+}
+
+func extractFieldValues(props map[string]any, defs map[string]any, vals map[string][]string) {
 	for fieldName, fieldDef := range props {
 		if fieldObj, ok := fieldDef.(map[string]any); ok {
+			fieldObj = resolveRef(fieldObj, defs)
 			// Check for enum
 			if enumVals, ok := fieldObj["enum"].([]any); ok {
 				values := make([]string, 0, len(enumVals))
@@ -120,9 +148,10 @@ func extractFieldValues(props map[string]any, vals map[string][]string) {
 	}
 }
 
-func extractFieldDesc(props map[string]any, desc map[string]string) {
+func extractFieldDesc(props map[string]any, defs map[string]any, desc map[string]string) {
 	for fieldName, fieldDef := range props {
 		if fieldObj, ok := fieldDef.(map[string]any); ok {
+			fieldObj = resolveRef(fieldObj, defs)
 			if description, ok := fieldObj["description"].(string); ok {
 				desc[fieldName] = description
 			}
@@ -142,6 +171,10 @@ func GetKeyFieldDescription(fieldName string) string {
 	return keyFieldDesc[fieldName]
 }
 
+func GetProfileFieldDescription(fieldName string) string {
+	return profileFieldDesc[fieldName]
+}
+
 func GetRootFields() []string {
 	return rootFields
 }
@@ -154,6 +187,10 @@ func GetKeyFields() []string {
 	return keyFields
 }
 
+func GetProfileFields() []string {
+	return profileFields
+}
+
 func GetRootFieldVals() map[string][]string {
 	return rootFieldVals
 }
@@ -165,3 +202,7 @@ func GetSecretFieldVals() map[string][]string {
 func GetKeyFieldVals() map[string][]string {
 	return keyFieldVals
 }
+
+func GetProfileFieldVals() map[string][]string {
+	return profileFieldVals
+}
@@ -0,0 +1,36 @@
+package lsp
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/BESTSELLER/harpocrates/secrets"
+)
+
+func TestSchemaFormatValues(t *testing.T) {
+	for name, vals := range map[string]map[string][]string{
+		"root":   GetRootFieldVals(),
+		"secret": GetSecretFieldVals(),
+	} {
+		if !slices.Equal(vals["format"], secrets.Formats()) {
+			t.Errorf("expected %s format values %v, got %v", name, secrets.Formats(), vals["format"])
+		}
+	}
+}
+
+func TestSchemaRefDescriptions(t *testing.T) {
+	if GetKeyFieldDescription("saveAsFile") == "" {
+		t.Errorf("expected a description for saveAsFile resolved through $ref")
+	}
+}
+
+func TestSchemaProfileFields(t *testing.T) {
+	for _, field := range []string{"variables", "prefix", "output"} {
+		if !slices.Contains(GetProfileFields(), field) {
+			t.Errorf("expected profile field %s in %v", field, GetProfileFields())
+		}
+	}
+	if !slices.Equal(GetProfileFieldVals()["format"], secrets.Formats()) {
+		t.Errorf("expected profile format values %v, got %v", secrets.Formats(), GetProfileFieldVals()["format"])
+	}
+}
@@ -120,7 +120,7 @@ func (s *Server) handleInitialize(req Request) {
 		Capabilities: ServerCapabilities{
 			TextDocumentSync: 1, // Full document sync
 			CompletionProvider: &CompletionOptions{
-				TriggerCharacters: []string{"/", "-", " ", "\n", "\r"},
+				TriggerCharacters: []string{"/", "-", " ", "\n", "\r", "{"},
 			},
 			HoverProvider: true,
 		},
@@ -262,6 +262,8 @@ func (s *Server) handleHover(req Request) {
 		description = GetSecretFieldDescription(key)
 	case ContextKeyObject:
 		description = GetKeyFieldDescription(key)
+	case ContextProfileObject:
+		description = GetProfileFieldDescription(key)
 	}
 
 	if description == "" {
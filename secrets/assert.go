@@ -0,0 +1,32 @@
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MatchPattern returns an error if the value doesn't match the regular expression pattern.
+// The value is matched as it is written, so objects are matched as JSON. Use ^ and $ to match the whole value.
+func MatchPattern(value any, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	if !re.MatchString(getPlainRepresentation(value)) {
+		// The value is a secret, so it is left out of the error
+		return fmt.Errorf("the value doesn't match the pattern '%s'", pattern)
+	}
+	return nil
+}
+
+// MissingKeys returns the keys that are not in the secret, in the order they are given.
+// The keys can use the dot notation and array brackets of Lookup.
+func MissingKeys(secret map[string]any, keys []string) []string {
+	var missing []string
+	for _, key := range keys {
+		if _, found := Lookup(secret, key); !found {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
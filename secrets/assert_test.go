@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		pattern string
+		wantErr string
+	}{
+		{"matching url", "https://example.com/db", `^https://`, ""},
+		{"malformed url", "example.com/db", `^https://`, "doesn't match the pattern"},
+		{"empty password", "", `.+`, "doesn't match the pattern"},
+		{"number", 5432, `^[0-9]+$`, ""},
+		{"invalid pattern", "value", `(`, "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MatchPattern(tt.value, tt.pattern)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+			if err != nil && strings.Contains(err.Error(), "example.com") {
+				t.Errorf("expected the value to be left out of the error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMissingKeys(t *testing.T) {
+	secret := map[string]any{
+		"username": "admin",
+		"database": map[string]any{"host": "localhost"},
+	}
+
+	got := MissingKeys(secret, []string{"username", "password", "database.host", "database.port"})
+	if want := []string{"password", "database.port"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
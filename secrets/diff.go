@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ChangeKind tells how a key differs between two results
+type ChangeKind string
+
+const (
+	// ChangeAdded is a key that is only in the new result
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is a key that is only in the old result
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeChanged is a key that has a different value in the new result
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a key that differs between two results
+type Change struct {
+	Key  string
+	Kind ChangeKind
+	// Old is the value in the old result, it is nil for added keys
+	Old any
+	// New is the value in the new result, it is nil for removed keys
+	New any
+}
+
+// Diff returns the keys that differ between the old and the new result, sorted by key.
+// Nested objects are compared key by key using dotted keys, and values are compared by their text, so 8080 and "8080" are equal.
+func Diff(oldResult Result, newResult Result) []Change {
+	oldValues, newValues := flatten(oldResult), flatten(newResult)
+
+	var changes []Change
+	for _, key := range slices.Sorted(maps.Keys(oldValues)) {
+		newValue, ok := newValues[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: key, Kind: ChangeRemoved, Old: oldValues[key]})
+		case getPlainRepresentation(oldValues[key]) != getPlainRepresentation(newValue):
+			changes = append(changes, Change{Key: key, Kind: ChangeChanged, Old: oldValues[key], New: newValue})
+		}
+	}
+	for key, value := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			changes = append(changes, Change{Key: key, Kind: ChangeAdded, New: value})
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int { return strings.Compare(a.Key, b.Key) })
+	return changes
+}
+
+// flatten returns the values of the result with nested objects expanded into dotted keys
+func flatten(result Result) map[string]any {
+	values := make(map[string]any, len(result))
+	for key, value := range result {
+		flattenValue(values, key, value)
+	}
+	return values
+}
+
+func flattenValue(values map[string]any, key string, value any) {
+	nested, ok := value.(map[string]any)
+	if !ok || len(nested) == 0 {
+		values[key] = value
+		return
+	}
+	for childKey, childValue := range nested {
+		flattenValue(values, key+"."+childKey, childValue)
+	}
+}
@@ -0,0 +1,38 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := Result{
+		"port":     8080,
+		"password": "old",
+		"removed":  "value",
+		"db":       map[string]any{"host": "localhost", "user": "app"},
+	}
+	current := Result{
+		"port":     "8080",
+		"password": "new",
+		"added":    "value",
+		"db":       map[string]any{"host": "db.internal", "user": "app"},
+	}
+
+	expected := []Change{
+		{Key: "added", Kind: ChangeAdded, New: "value"},
+		{Key: "db.host", Kind: ChangeChanged, Old: "localhost", New: "db.internal"},
+		{Key: "password", Kind: ChangeChanged, Old: "old", New: "new"},
+		{Key: "removed", Kind: ChangeRemoved, Old: "value"},
+	}
+	if got := Diff(old, current); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	result := Result{"key": "value", "nested": map[string]any{"key": true}}
+	if got := Diff(result, result); got != nil {
+		t.Errorf("expected no changes, got %+v", got)
+	}
+}
@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PolicyWarn keeps the value that was seen last, like PolicyLastWins, and logs a warning
+const PolicyWarn ConflictPolicy = "warn"
+
+// DuplicatePolicies lists the policies that can be used when two secrets give the same key in one output
+var DuplicatePolicies = []ConflictPolicy{PolicyError, PolicyWarn, PolicyFirstWins, PolicyLastWins}
+
+// Source tells where in Vault a value comes from, and how its key was named
+type Source struct {
+	Path string
+	Key  string
+	// Prefix and UpperCase are what the key was added with
+	Prefix    string
+	UpperCase bool
+	// PrefixFrom and UpperCaseFrom name the level of the spec the prefix and uppercase were set on, e.g. root, secret or key
+	PrefixFrom    string
+	UpperCaseFrom string
+}
+
+func (source Source) String() string {
+	return fmt.Sprintf("the key '%s' in '%s'", source.Key, source.Path)
+}
+
+// Collector adds secrets to a Result, handling keys that are added more than once with a different value according to its policy
+type Collector struct {
+	Result Result
+	// Order holds the keys in the order they were first added
+	Order []string
+	// Sources holds where the value of each key comes from
+	Sources map[string]Source
+	policy  ConflictPolicy
+}
+
+// NewCollector returns a Collector adding to an empty Result
+func NewCollector(policy ConflictPolicy) *Collector {
+	return &Collector{Result: make(Result), Sources: map[string]Source{}, policy: policy}
+}
+
+// Add adds the value with the prefix and case applied to its key, like Result.Add.
+// It returns false when the value is not added because of the policy, and an error when the policy is PolicyError.
+func (c *Collector) Add(key string, value any, prefix string, upperCase bool, source Source) (bool, error) {
+	source.Prefix, source.UpperCase = prefix, upperCase
+	return c.Set(ToUpperOrNotToUpper(fmt.Sprintf("%s%s", prefix, key), &upperCase), value, source)
+}
+
+// Set adds the value with the exact key, see Add
+func (c *Collector) Set(key string, value any, source Source) (bool, error) {
+	existing, exists := c.Result[key]
+	previous := c.Sources[key]
+	if exists && getPlainRepresentation(existing) != getPlainRepresentation(value) {
+		message := fmt.Sprintf("the key '%s' is set by both %s and %s", key, previous, source)
+		switch c.policy {
+		case PolicyError:
+			return false, fmt.Errorf("%s", message)
+		case PolicyFirstWins:
+			log.Debug().Msgf("%s, keeping the first", message)
+			return false, nil
+		case PolicyWarn:
+			log.Warn().Msgf("%s, keeping the last", message)
+		default:
+			log.Debug().Msgf("%s, keeping the last", message)
+		}
+	}
+
+	if !exists {
+		c.Order = append(c.Order, key)
+	}
+	c.Result[key] = value
+	c.Sources[key] = source
+	return true, nil
+}
+
+// warnEnvNameCollisions logs a warning for keys that are different but give the same environment variable name
+func (result Result) warnEnvNameCollisions() {
+	collisions := result.envNameCollisions()
+	names := make([]string, 0, len(collisions))
+	for name := range collisions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		log.Warn().Msgf("The keys '%s' all give the environment variable '%s', only one of them is used", strings.Join(collisions[name], "', '"), name)
+	}
+}
+
+// envNameCollisions returns the sorted keys by the environment variable name they share with another key
+func (result Result) envNameCollisions() map[string][]string {
+	keysByName := map[string][]string{}
+	for key := range result {
+		name := fixEnvName(key)
+		keysByName[name] = append(keysByName[name], key)
+	}
+
+	collisions := map[string][]string{}
+	for name, keys := range keysByName {
+		if len(keys) > 1 {
+			slices.Sort(keys)
+			collisions[name] = keys
+		}
+	}
+	return collisions
+}
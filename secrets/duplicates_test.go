@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollector(t *testing.T) {
+	first := Source{Path: "secret/data/a", Key: "password"}
+	second := Source{Path: "secret/data/b", Key: "pw"}
+
+	tests := []struct {
+		policy    ConflictPolicy
+		wantValue any
+		wantAdded bool
+		wantErr   string
+	}{
+		{PolicyError, "first", false, "the key 'APP_PASSWORD' is set by both the key 'password' in 'secret/data/a' and the key 'pw' in 'secret/data/b'"},
+		{PolicyWarn, "second", true, ""},
+		{PolicyFirstWins, "first", false, ""},
+		{PolicyLastWins, "second", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			collector := NewCollector(tt.policy)
+			if _, err := collector.Add("password", "first", "APP_", true, first); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			added, err := collector.Add("PASSWORD", "second", "app_", true, second)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if added != tt.wantAdded {
+				t.Errorf("expected added to be %v, got %v", tt.wantAdded, added)
+			}
+			if value := collector.Result["APP_PASSWORD"]; value != tt.wantValue {
+				t.Errorf("expected %v, got %v", tt.wantValue, value)
+			}
+		})
+	}
+}
+
+func TestCollectorSameValue(t *testing.T) {
+	collector := NewCollector(PolicyError)
+	for _, path := range []string{"secret/data/a", "secret/data/b"} {
+		if _, err := collector.Add("region", "europe-west1", "", false, Source{Path: path, Key: "region"}); err != nil {
+			t.Errorf("expected the same value not to be a duplicate, got %v", err)
+		}
+	}
+}
+
+func TestCollectorSources(t *testing.T) {
+	collector := NewCollector(PolicyError)
+	if _, err := collector.Add("password", "secret", "APP_", true, Source{Path: "secret/data/a", Key: "pw", PrefixFrom: "key", UpperCaseFrom: "root"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := Source{Path: "secret/data/a", Key: "pw", Prefix: "APP_", UpperCase: true, PrefixFrom: "key", UpperCaseFrom: "root"}
+	if got := collector.Sources["APP_PASSWORD"]; got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestEnvNameCollisions(t *testing.T) {
+	result := Result{"db.host": "a", "db_host": "b", "db-host": "c", "port": "5432"}
+
+	expected := map[string][]string{"db_host": {"db-host", "db.host", "db_host"}}
+	if got := result.envNameCollisions(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestCollectorOrder(t *testing.T) {
+	collector := NewCollector(PolicyLastWins)
+	for _, key := range []string{"b", "a", "b", "c"} {
+		if _, err := collector.Add(key, key, "", false, Source{Path: "secret/data/a", Key: key}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	expected := []string{"b", "a", "c"}
+	if !reflect.DeepEqual(collector.Order, expected) {
+		t.Errorf("expected %v, got %v", expected, collector.Order)
+	}
+}
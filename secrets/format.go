@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/rs/zerolog/log"
 	"go.yaml.in/yaml/v4"
@@ -16,6 +18,12 @@ type Result map[string]any
 // envNameRegexp is a precompiled regular expression used by fixEnvName
 var envNameRegexp = regexp.MustCompile("[^a-zA-Z0-9_]+")
 
+// iniNameRegexp is a precompiled regular expression used by fixININame
+var iniNameRegexp = regexp.MustCompile("[^a-zA-Z0-9_.-]+")
+
+// tomlBareKeyRegexp matches keys that can be written without quotes in TOML
+var tomlBareKeyRegexp = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
 // Add will add a new secret to the Result
 func (result Result) Add(key string, value any, prefix string, upperCase bool) {
 	result[ToUpperOrNotToUpper(fmt.Sprintf("%s%s", prefix, key), &upperCase)] = value
@@ -23,18 +31,39 @@ func (result Result) Add(key string, value any, prefix string, upperCase bool) {
 
 // ToJSON will format a map[string]any to json
 func (result Result) ToJSON() string {
+	return result.toJSON(result.Keys(nil))
+}
+
+func (result Result) toJSON(keys []string) string {
 	log.Debug().Msg("Exporting as JSON")
-	jsonString, err := json.Marshal(result)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Unable to convert result to json")
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to convert result to json")
+		}
+		valueJSON, err := json.Marshal(result[key])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to convert result to json")
+		}
+		sb.Write(keyJSON)
+		sb.WriteString(":")
+		sb.Write(valueJSON)
 	}
-	return string(jsonString)
+	sb.WriteString("}")
+	return sb.String()
 }
 
-func (result Result) toKV(prefix string) string {
+func (result Result) toKV(prefix string, keys []string) string {
 	var returnString string
+	result.warnEnvNameCollisions()
 
-	for key, val := range result {
+	for _, key := range keys {
+		val := result[key]
 		leKey := fixEnvName(key)
 		log.Info().Msgf("Exporting key: %s", leKey)
 		returnString += fmt.Sprintf("%s%s=%s\n", prefix, leKey, getStringRepresentation(val))
@@ -44,18 +73,24 @@ func (result Result) toKV(prefix string) string {
 
 // ToKVarray converts the result to a key=value array
 func (result Result) ToKVarray(prefix string) (returnString []string) {
-	for key, val := range result {
+	result.warnEnvNameCollisions()
+	for _, key := range result.Keys(nil) {
+		val := result[key]
 		leKey := fixEnvName(key)
 		log.Debug().Msgf("Exporting key: %s", leKey)
+		if bytes, isBytes := val.([]byte); isBytes {
+			val = string(bytes)
+		}
 		returnString = append(returnString, fmt.Sprintf("%s%s=%v", prefix, leKey, val))
 	}
 	return returnString
 }
 
-func (result Result) toSecretKV() string {
+func (result Result) toSecretKV(keys []string) string {
 	var returnString string
 
-	for key, val := range result {
+	for _, key := range keys {
+		val := result[key]
 		log.Info().Msgf("Exporting key: %s", key)
 		returnString += fmt.Sprintf("%s=%s\n", key, getStringRepresentation(val))
 	}
@@ -66,26 +101,107 @@ func (result Result) toSecretKV() string {
 //
 // export KEY='value'
 func (result Result) ToENV() string {
+	return result.toENV(result.Keys(nil))
+}
+
+func (result Result) toENV(keys []string) string {
 	log.Debug().Msg("Exporting as env values")
-	return result.toKV("export ")
+	return result.toKV("export ", keys)
 }
 
 // ToK8sSecret exports secrets as raw key values
 func (result Result) ToK8sSecret() string {
+	return result.toK8sSecret(result.Keys(nil))
+}
+
+func (result Result) toK8sSecret(keys []string) string {
 	log.Debug().Msg("Exporting as raw key values")
-	return result.toSecretKV()
+	return result.toSecretKV(keys)
 }
 
 // ToYAML exports secrets as yaml
 func (result Result) ToYAML() string {
+	return result.toYAML(result.Keys(nil))
+}
+
+func (result Result) toYAML(keys []string) string {
 	log.Debug().Msg("Exporting as YAML")
-	yamlString, err := yaml.Marshal(result)
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		var keyNode, valueNode yaml.Node
+		if err := keyNode.Encode(key); err != nil {
+			log.Fatal().Err(err).Msg("Unable to convert result to yaml")
+		}
+		if err := valueNode.Encode(result[key]); err != nil {
+			log.Fatal().Err(err).Msg("Unable to convert result to yaml")
+		}
+		mapping.Content = append(mapping.Content, &keyNode, &valueNode)
+	}
+	yamlString, err := yaml.Marshal(mapping)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Unable to convert result to yaml")
 	}
 	return string(yamlString)
 }
 
+// ToProperties exports secrets as a Java properties file
+//
+// key=value
+func (result Result) ToProperties() string {
+	return result.toProperties(result.Keys(nil))
+}
+
+func (result Result) toProperties(keys []string) string {
+	log.Debug().Msg("Exporting as Java properties")
+	var returnString string
+
+	for _, key := range keys {
+		val := result[key]
+		log.Info().Msgf("Exporting key: %s", key)
+		returnString += fmt.Sprintf("%s=%s\n", escapeProperties(key, true), escapeProperties(getPlainRepresentation(val), false))
+	}
+	return returnString
+}
+
+// ToTOML exports secrets as a flat TOML document
+//
+// key = "value"
+func (result Result) ToTOML() string {
+	return result.toTOML(result.Keys(nil))
+}
+
+func (result Result) toTOML(keys []string) string {
+	log.Debug().Msg("Exporting as TOML")
+	var returnString string
+
+	for _, key := range keys {
+		val := result[key]
+		log.Info().Msgf("Exporting key: %s", key)
+		returnString += fmt.Sprintf("%s = %s\n", tomlKey(key), getTOMLRepresentation(val))
+	}
+	return returnString
+}
+
+// ToINI exports secrets as an INI file without sections
+//
+// key="value"
+func (result Result) ToINI() string {
+	return result.toINI(result.Keys(nil))
+}
+
+func (result Result) toINI(keys []string) string {
+	log.Debug().Msg("Exporting as INI")
+	var returnString string
+
+	for _, key := range keys {
+		val := result[key]
+		leKey := fixININame(key)
+		log.Info().Msgf("Exporting key: %s", leKey)
+		returnString += fmt.Sprintf("%s=%s\n", leKey, getINIRepresentation(val))
+	}
+	return returnString
+}
+
 // fixEnvName replaces all unsupported env characters with "_"
 func fixEnvName(currentName string) string {
 	envVar := envNameRegexp.ReplaceAllString(currentName, "_")
@@ -117,3 +233,149 @@ func getStringRepresentation(val any) string {
 		return fmt.Sprintf("'%s'", val)
 	}
 }
+
+// PlainValue returns a value the way the flat formats write it, e.g. a map as json
+func PlainValue(value any) string {
+	return getPlainRepresentation(value)
+}
+
+// getPlainRepresentation returns the unquoted text of a value, nested maps and arrays are encoded as json
+func getPlainRepresentation(val any) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(jsonBytes)
+	}
+}
+
+// escapeProperties escapes a key or value as described in the java.util.Properties documentation.
+// Characters outside of ISO 8859-1 are written as \uXXXX escapes.
+func escapeProperties(text string, isKey bool) string {
+	var sb strings.Builder
+	for i, r := range text {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if isKey {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(r)
+		case ' ':
+			// Leading whitespace in values would otherwise be stripped by the reader
+			if isKey || i == 0 {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(r)
+		default:
+			if r < 0x20 || r > 0xff {
+				writeUnicodeEscape(&sb, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// tomlKey returns key as a bare key when possible, otherwise as a quoted key
+func tomlKey(key string) string {
+	if tomlBareKeyRegexp.MatchString(key) {
+		return key
+	}
+	return quoteTOML(key)
+}
+
+func getTOMLRepresentation(val any) string {
+	switch v := val.(type) {
+	case int, float64, bool:
+		return getPlainRepresentation(v)
+	default:
+		return quoteTOML(getPlainRepresentation(v))
+	}
+}
+
+// quoteTOML returns text as a TOML basic string
+func quoteTOML(text string) string {
+	var sb strings.Builder
+	sb.WriteRune('"')
+	for _, r := range text {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				writeUnicodeEscape(&sb, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteRune('"')
+	return sb.String()
+}
+
+// fixININame replaces all characters that are not safe in an INI key with "_"
+func fixININame(currentName string) string {
+	return iniNameRegexp.ReplaceAllString(currentName, "_")
+}
+
+// getINIRepresentation always double quotes strings, so values containing ; # = or reserved words
+// such as "yes" and "null" are read back as plain strings.
+func getINIRepresentation(val any) string {
+	switch v := val.(type) {
+	case int, float64:
+		return getPlainRepresentation(v)
+	case bool:
+		return fmt.Sprintf(`"%t"`, v)
+	default:
+		text := getPlainRepresentation(v)
+		replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+		return `"` + replacer.Replace(text) + `"`
+	}
+}
+
+// writeUnicodeEscape writes r as one or two (surrogate pair) \uXXXX escapes
+func writeUnicodeEscape(sb *strings.Builder, r rune) {
+	if r > 0xffff {
+		r1, r2 := utf16.EncodeRune(r)
+		fmt.Fprintf(sb, `\u%04X\u%04X`, r1, r2)
+		return
+	}
+	fmt.Fprintf(sb, `\u%04X`, r)
+}
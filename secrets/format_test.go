@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"testing"
+)
+
+func TestToProperties(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Result
+		expected string
+	}{
+		{"plain", Result{"key": "value"}, "key=value\n"},
+		{"separators in key", Result{"a key=b:c": "value"}, "a\\ key\\=b\\:c=value\n"},
+		{"leading whitespace and newlines", Result{"key": " multi\nline\\"}, "key=\\ multi\\nline\\\\\n"},
+		{"non latin-1", Result{"key": "ø€"}, "key=ø\\u20AC\n"},
+		{"number", Result{"key": float64(123)}, "key=123\n"},
+		{"nested", Result{"key": map[string]any{"a": "b"}}, "key={\"a\":\"b\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.result.ToProperties(); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Result
+		expected string
+	}{
+		{"plain", Result{"key": "value"}, "key = \"value\"\n"},
+		{"quoted key", Result{"my.key": "value"}, "\"my.key\" = \"value\"\n"},
+		{"escapes", Result{"key": "a\"b\\c\nd\x01"}, "key = \"a\\\"b\\\\c\\nd\\u0001\"\n"},
+		{"number", Result{"key": float64(1.5)}, "key = 1.5\n"},
+		{"bool", Result{"key": true}, "key = true\n"},
+		{"nil", Result{"key": nil}, "key = \"\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.result.ToTOML(); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToINI(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Result
+		expected string
+	}{
+		{"plain", Result{"key": "value"}, "key=\"value\"\n"},
+		{"unsafe key", Result{"a key[0]": "value"}, "a_key_0_=\"value\"\n"},
+		{"escapes", Result{"key": "a;b\"c\\d\ne"}, "key=\"a;b\\\"c\\\\d\\ne\"\n"},
+		{"reserved word", Result{"key": "yes"}, "key=\"yes\"\n"},
+		{"number", Result{"key": float64(42)}, "key=42\n"},
+		{"bool", Result{"key": false}, "key=\"false\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.result.ToINI(); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormatsAreRegistered(t *testing.T) {
+	for _, format := range []string{"env", "ini", "json", "properties", "secret", "toml", "yaml"} {
+		if _, ok := GetFormatter(format); !ok {
+			t.Errorf("expected format %q to be registered", format)
+		}
+	}
+	if IsFormat("xml") {
+		t.Errorf("did not expect format %q to be registered", "xml")
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	tests := []struct {
+		format   string
+		key      string
+		expected string
+	}{
+		{"env", "db.host", "db_host"},
+		{"ini", "db host", "db_host"},
+		{"json", "db.host", "db.host"},
+		{"xml", "db.host", "db.host"},
+	}
+
+	for _, tt := range tests {
+		if actual := KeyName(tt.format, tt.key); actual != tt.expected {
+			t.Errorf("expected %q for %q in %s, got %q", tt.expected, tt.key, tt.format, actual)
+		}
+	}
+}
+
+func TestDecodedValueAsText(t *testing.T) {
+	decoded, err := Transform("aGVsbG8=", []any{"base64decode"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	result := Result{"K": AsText(decoded)}
+
+	if actual := result.ToJSON(); actual != `{"K":"hello"}` {
+		t.Errorf("expected the json to have the decoded text, got %q", actual)
+	}
+	if actual := result.ToYAML(); actual != "K: hello\n" {
+		t.Errorf("expected the yaml to have the decoded text, got %q", actual)
+	}
+	if _, isInt := AsText(42).(int); !isInt {
+		t.Error("expected values that are not bytes to be left as is")
+	}
+}
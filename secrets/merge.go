@@ -0,0 +1,294 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// ConflictPolicy decides what happens when a key is defined more than once with different values
+type ConflictPolicy string
+
+const (
+	// PolicyError fails the run
+	PolicyError ConflictPolicy = "error"
+	// PolicyFirstWins keeps the value that was seen first
+	PolicyFirstWins ConflictPolicy = "first-wins"
+	// PolicyLastWins keeps the value that was seen last
+	PolicyLastWins ConflictPolicy = "last-wins"
+)
+
+// MergePolicies lists the policies that can be used when merging into an existing file
+var MergePolicies = []ConflictPolicy{PolicyError, PolicyFirstWins, PolicyLastWins}
+
+// ParseConflictPolicy returns the policy with the given name, an empty name gives the default
+func ParseConflictPolicy(name string, allowed []ConflictPolicy, defaultPolicy ConflictPolicy) (ConflictPolicy, error) {
+	if name == "" {
+		return defaultPolicy, nil
+	}
+	for _, policy := range allowed {
+		if string(policy) == name {
+			return policy, nil
+		}
+	}
+
+	names := make([]string, len(allowed))
+	for i, policy := range allowed {
+		names[i] = string(policy)
+	}
+	return "", fmt.Errorf("unknown conflict policy '%s', please use either: %s", name, strings.Join(names, ", "))
+}
+
+// Merge reads the existing content of a file in the given format and merges the incoming secrets into it.
+// Objects are merged key by key, so the result of running the same spec twice is the same as running it once.
+func Merge(name string, existing string, incoming Result, options FormatOptions, policy ConflictPolicy) (Result, error) {
+	f, ok := formats[name]
+	if !ok || f.parse == nil {
+		return nil, fmt.Errorf("the format '%s' can't be merged", name)
+	}
+
+	merged, err := f.parse(existing)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the existing %s content: %w", name, err)
+	}
+
+	if options.Nested && f.nested {
+		incoming, err = incoming.Nest()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for key, value := range incoming {
+		if f.keyName != nil {
+			key = f.keyName(key)
+		}
+		if err := mergeValue(merged, key, value, key, policy); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+func mergeValue(current map[string]any, key string, value any, path string, policy ConflictPolicy) error {
+	existing, exists := current[key]
+	if !exists {
+		current[key] = value
+		return nil
+	}
+
+	existingMap, existingIsMap := existing.(map[string]any)
+	valueMap, valueIsMap := value.(map[string]any)
+	if existingIsMap && valueIsMap {
+		for childKey, childValue := range valueMap {
+			if err := mergeValue(existingMap, childKey, childValue, path+"."+childKey, policy); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !existingIsMap && !valueIsMap && getPlainRepresentation(existing) == getPlainRepresentation(value) {
+		current[key] = value
+		return nil
+	}
+
+	switch policy {
+	case PolicyError:
+		return fmt.Errorf("the key '%s' already exists with a different value", path)
+	case PolicyFirstWins:
+		return nil
+	default:
+		current[key] = value
+		return nil
+	}
+}
+
+func parseJSON(content string) (Result, error) {
+	result := make(Result)
+	if strings.TrimSpace(content) == "" {
+		return result, nil
+	}
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseYAML(content string) (Result, error) {
+	result := make(Result)
+	if err := yaml.Unmarshal([]byte(content), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseENV reads files written by ToENV
+func parseENV(content string) (Result, error) {
+	return parseKV(content, "export ")
+}
+
+// parseSecretKV reads files written by ToK8sSecret
+func parseSecretKV(content string) (Result, error) {
+	return parseKV(content, "")
+}
+
+// parseKV reads key=value lines, values in single quotes may span multiple lines
+func parseKV(content string, prefix string) (Result, error) {
+	result := make(Result)
+	lines := strings.Split(content, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, prefix)
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d is not a key=value pair", i+1)
+		}
+
+		if strings.HasPrefix(value, "'") {
+			for len(value) < 2 || !strings.HasSuffix(value, "'") {
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("the value of '%s' is missing a closing quote", key)
+				}
+				value += "\n" + lines[i]
+			}
+			result[key] = value[1 : len(value)-1]
+			continue
+		}
+		result[key] = parseScalar(value)
+	}
+	return result, nil
+}
+
+// parseScalar reads an unquoted value as written by getStringRepresentation
+func parseScalar(value string) any {
+	switch value {
+	case "null":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number
+	}
+	return value
+}
+
+// parseProperties reads a Java properties file, as described in the java.util.Properties documentation
+func parseProperties(content string) (Result, error) {
+	result := make(Result)
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// A line ending with an odd number of backslashes continues on the next line
+		for endsWithEscape(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		key, value := splitProperty(line)
+		unescapedKey, err := unescapeProperties(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		unescapedValue, err := unescapeProperties(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		result[unescapedKey] = unescapedValue
+	}
+	return result, nil
+}
+
+func endsWithEscape(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+// splitProperty splits a line at the first unescaped '=', ':' or whitespace
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			value := strings.TrimLeft(line[i:], " \t\f")
+			if value != "" && (value[0] == '=' || value[0] == ':') {
+				value = strings.TrimLeft(value[1:], " \t\f")
+			}
+			return line[:i], value
+		}
+	}
+	return line, ""
+}
+
+func unescapeProperties(text string) (string, error) {
+	if !strings.Contains(text, `\`) {
+		return text, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 >= len(text) {
+			sb.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(text) {
+				return "", fmt.Errorf("malformed \\uXXXX escape")
+			}
+			code, err := strconv.ParseUint(text[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uXXXX escape")
+			}
+			i += 4
+			// Characters outside of the basic plane are written as a surrogate pair
+			if utf16.IsSurrogate(rune(code)) && i+6 < len(text) && text[i+1] == '\\' && text[i+2] == 'u' {
+				if low, err := strconv.ParseUint(text[i+3:i+7], 16, 32); err == nil {
+					if r := utf16.DecodeRune(rune(code), rune(low)); r != unicode.ReplacementChar {
+						sb.WriteRune(r)
+						i += 6
+						continue
+					}
+				}
+			}
+			sb.WriteRune(rune(code))
+		default:
+			sb.WriteByte(text[i])
+		}
+	}
+	return sb.String(), nil
+}
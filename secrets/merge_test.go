@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"testing"
+)
+
+func TestMergeIsIdempotent(t *testing.T) {
+	incoming := Result{"user-name": "app", "password": "it's\nmultiline", "port": float64(5432), "enabled": true}
+
+	for _, format := range []string{"env", "json", "properties", "secret", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			render, _ := GetFormatter(format)
+			first, err := render(incoming, FormatOptions{})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			merged, err := Merge(format, first, incoming, FormatOptions{}, PolicyError)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(merged) != len(incoming) {
+				t.Errorf("expected %d keys, got %d: %v", len(incoming), len(merged), merged)
+			}
+
+			second, err := render(merged, FormatOptions{})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			reparsed, err := formats[format].parse(second)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			for key, value := range merged {
+				if getPlainRepresentation(reparsed[key]) != getPlainRepresentation(value) {
+					t.Errorf("expected %s to be %v, got %v", key, value, reparsed[key])
+				}
+			}
+		})
+	}
+}
+
+func TestMergePolicies(t *testing.T) {
+	existing := `{"a":"old","b":"kept"}`
+	incoming := Result{"a": "new", "c": "added"}
+
+	tests := []struct {
+		policy   ConflictPolicy
+		expected string
+		wantErr  bool
+	}{
+		{PolicyLastWins, `{"a":"new","b":"kept","c":"added"}`, false},
+		{PolicyFirstWins, `{"a":"old","b":"kept","c":"added"}`, false},
+		{PolicyError, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			merged, err := Merge("json", existing, incoming, FormatOptions{}, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && merged.ToJSON() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, merged.ToJSON())
+			}
+		})
+	}
+}
+
+func TestMergeNested(t *testing.T) {
+	existing := `{"db":{"host":"localhost","username":"app"}}`
+	incoming := Result{"db.username": "app", "db.password": "s3cr3t"}
+
+	merged, err := Merge("json", existing, incoming, FormatOptions{Nested: true}, PolicyError)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `{"db":{"host":"localhost","password":"s3cr3t","username":"app"}}`
+	if actual := merged.ToJSON(); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestMergeENVMatchesWrittenKeyNames(t *testing.T) {
+	merged, err := Merge("env", "export my_key='old'\n", Result{"my-key": "new"}, FormatOptions{}, PolicyLastWins)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(merged) != 1 || merged["my_key"] != "new" {
+		t.Errorf("expected my_key to be replaced, got %v", merged)
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	content := "# comment\n! comment\nspace\\ key = value\ncolon:value\nmulti = line one \\\n    line two\nunicode=\\u20AC\\uD83D\\uDE00\nempty\n"
+
+	result, err := parseProperties(content)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := map[string]string{"space key": "value", "colon": "value", "multi": "line one line two", "unicode": "€😀", "empty": ""}
+	for key, value := range expected {
+		if result[key] != value {
+			t.Errorf("expected %q to be %q, got %q", key, value, result[key])
+		}
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	policy, err := ParseConflictPolicy("", MergePolicies, PolicyLastWins)
+	if err != nil || policy != PolicyLastWins {
+		t.Errorf("expected the default policy, got %q, %v", policy, err)
+	}
+	if _, err := ParseConflictPolicy("warn", MergePolicies, PolicyLastWins); err == nil {
+		t.Errorf("expected an error for an unknown policy")
+	}
+}
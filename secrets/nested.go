@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Nest returns a copy of the result where dotted keys are expanded into nested objects,
+// e.g. "db.username" becomes {"db": {"username": ...}}.
+// String values holding a JSON object are decoded, so they are kept as structure instead of a serialized blob.
+func (result Result) Nest() (Result, error) {
+	nested := make(Result)
+
+	// Sorting makes sure that conflicts are reported the same way on every run
+	keys := make([]string, 0, len(result))
+	for key := range result {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if err := setNested(nested, key, decodeJSONObject(result[key])); err != nil {
+			return nil, err
+		}
+	}
+	return nested, nil
+}
+
+func setNested(nested map[string]any, key string, value any) error {
+	segments := strings.Split(key, ".")
+	current := nested
+
+	for i, segment := range segments[:len(segments)-1] {
+		next, exists := current[segment]
+		if !exists {
+			child := make(map[string]any)
+			current[segment] = child
+			current = child
+			continue
+		}
+		child, isMap := next.(map[string]any)
+		if !isMap {
+			return fmt.Errorf("the key '%s' conflicts with the key '%s'", key, strings.Join(segments[:i+1], "."))
+		}
+		current = child
+	}
+
+	return mergeNested(current, segments[len(segments)-1], value, key)
+}
+
+// mergeNested sets value at current[name], merging it with an existing object of the same name
+func mergeNested(current map[string]any, name string, value any, path string) error {
+	existing, exists := current[name]
+	if !exists {
+		current[name] = copyNested(value)
+		return nil
+	}
+
+	existingMap, existingIsMap := existing.(map[string]any)
+	valueMap, valueIsMap := value.(map[string]any)
+	if !existingIsMap || !valueIsMap {
+		return fmt.Errorf("the key '%s' is defined more than once", path)
+	}
+	for childName, childValue := range valueMap {
+		if err := mergeNested(existingMap, childName, childValue, path+"."+childName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyNested copies nested objects, so merging into them later doesn't change the original result
+func copyNested(value any) any {
+	valueMap, isMap := value.(map[string]any)
+	if !isMap {
+		return value
+	}
+	copied := make(map[string]any, len(valueMap))
+	for name, child := range valueMap {
+		copied[name] = copyNested(child)
+	}
+	return copied
+}
+
+// decodeJSONObject returns the decoded object if value is a string holding a JSON object, otherwise value is returned as is.
+func decodeJSONObject(value any) any {
+	text, isString := value.(string)
+	if !isString || !strings.HasPrefix(strings.TrimSpace(text), "{") {
+		return value
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal([]byte(text), &object); err != nil {
+		return value
+	}
+	return object
+}
@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"testing"
+)
+
+func TestNest(t *testing.T) {
+	result := Result{
+		"db.username": "app",
+		"db.password": "s3cr3t",
+		"config":      `{"feature":{"enabled":true}}`,
+		"plain":       "{not json",
+	}
+
+	nested, err := result.Nest()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `{"config":{"feature":{"enabled":true}},"db":{"password":"s3cr3t","username":"app"},"plain":"{not json"}`
+	if actual := nested.ToJSON(); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	if _, ok := result["db.username"]; !ok {
+		t.Errorf("expected the original result to be left untouched")
+	}
+}
+
+func TestNestMergesObjects(t *testing.T) {
+	original := map[string]any{"username": "app"}
+	result := Result{"db": original, "db.password": "s3cr3t"}
+
+	nested, err := result.Nest()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `{"db":{"password":"s3cr3t","username":"app"}}`
+	if actual := nested.ToJSON(); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+	if len(original) != 1 {
+		t.Errorf("expected the original value to be left untouched, got %v", original)
+	}
+}
+
+func TestNestConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+	}{
+		{"value and object", Result{"db": "value", "db.username": "app"}},
+		{"duplicate in object", Result{"db": map[string]any{"username": "a"}, "db.username": "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.result.Nest(); err == nil {
+				t.Errorf("expected an error for %v", tt.result)
+			}
+		})
+	}
+}
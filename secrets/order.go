@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// OrderSorted writes the keys sorted by name
+	OrderSorted = "sorted"
+	// OrderSpec writes the keys in the order they are listed in the spec
+	OrderSpec = "spec"
+)
+
+// KeyOrders lists the orders the keys of an output can be written in
+var KeyOrders = []string{OrderSorted, OrderSpec}
+
+// ParseKeyOrder checks the name of a key order, an empty name gives OrderSorted
+func ParseKeyOrder(name string) (string, error) {
+	if name == "" {
+		return OrderSorted, nil
+	}
+	if !slices.Contains(KeyOrders, name) {
+		return "", fmt.Errorf("unknown order '%s', please use either: %s", name, strings.Join(KeyOrders, ", "))
+	}
+	return name, nil
+}
+
+// Keys returns the keys of the result in the given order, followed by the keys that are not in it sorted by name.
+// A nil order gives all keys sorted, so the output is the same on every run.
+func (result Result) Keys(order []string) []string {
+	keys := make([]string, 0, len(result))
+	seen := make(map[string]bool, len(result))
+	for _, key := range order {
+		if _, ok := result[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	rest := make([]string, 0, len(result)-len(keys))
+	for key := range result {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	slices.Sort(rest)
+	return append(keys, rest...)
+}
+
+// nestedOrder returns the order of the top level keys of a nested result, which is where their first dotted key is in order
+func nestedOrder(order []string) []string {
+	nested := make([]string, 0, len(order))
+	for _, key := range order {
+		name, _, _ := strings.Cut(key, ".")
+		if !slices.Contains(nested, name) {
+			nested = append(nested, name)
+		}
+	}
+	return nested
+}
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	result := Result{"b": 1, "a": 2, "d": 3, "c": 4}
+
+	tests := []struct {
+		name     string
+		order    []string
+		expected []string
+	}{
+		{"sorted", nil, []string{"a", "b", "c", "d"}},
+		{"spec", []string{"d", "b", "a", "c"}, []string{"d", "b", "a", "c"}},
+		{"rest sorted", []string{"c"}, []string{"c", "a", "b", "d"}},
+		{"unknown and repeated keys", []string{"x", "b", "b"}, []string{"b", "a", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := result.Keys(tt.order); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseKeyOrder(t *testing.T) {
+	if order, err := ParseKeyOrder(""); err != nil || order != OrderSorted {
+		t.Errorf("expected %s, got %s (%v)", OrderSorted, order, err)
+	}
+	if order, err := ParseKeyOrder(OrderSpec); err != nil || order != OrderSpec {
+		t.Errorf("expected %s, got %s (%v)", OrderSpec, order, err)
+	}
+	if _, err := ParseKeyOrder("random"); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
+
+func TestOrderedFormats(t *testing.T) {
+	result := Result{"b": "2", "a": "1", "c": "3"}
+	options := FormatOptions{Order: []string{"c", "a", "b"}}
+
+	tests := map[string]string{
+		"env":        "export c='3'\nexport a='1'\nexport b='2'\n",
+		"json":       `{"c":"3","a":"1","b":"2"}`,
+		"yaml":       "c: \"3\"\na: \"1\"\nb: \"2\"\n",
+		"properties": "c=3\na=1\nb=2\n",
+	}
+
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			render, _ := GetFormatter(format)
+			got, err := render(result, options)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got != expected {
+				t.Errorf("expected %q, got %q", expected, got)
+			}
+
+			sorted, _ := render(result, FormatOptions{})
+			again, _ := render(result, FormatOptions{})
+			if sorted != again {
+				t.Errorf("expected the sorted output to be the same on every run, got %q and %q", sorted, again)
+			}
+		})
+	}
+}
+
+func TestNestedOrder(t *testing.T) {
+	expected := []string{"db", "port"}
+	if got := nestedOrder([]string{"db.user", "port", "db.password"}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
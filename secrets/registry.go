@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"slices"
+)
+
+// FormatOptions holds settings that are only used by some formats
+type FormatOptions struct {
+	// Template is the path to the text/template file used by the template format
+	Template string
+	// Nested expands dotted keys and JSON objects into nested structures for the structured formats
+	Nested bool
+	// Order is the order to write the keys in, keys that are not in it are written after them sorted by name.
+	// A nil Order writes all keys sorted.
+	Order []string
+}
+
+// Formatter renders a Result into the content of an output file
+type Formatter func(result Result, options FormatOptions) (string, error)
+
+// Parser reads the content of an output file back into a Result
+type Parser func(content string) (Result, error)
+
+// format describes an output format
+type format struct {
+	render Formatter
+	// parse is nil for formats that can't be read back, they are appended to as raw text
+	parse Parser
+	// nested marks formats that can represent nested structures
+	nested bool
+	// keyName returns the name a key is written as, so existing keys can be matched when merging, it is nil for formats that write keys as they are
+	keyName func(key string) string
+}
+
+// formats holds every supported output format, keyed by the name used in the spec and the --format flag.
+// Validation, the JSON schema and the LSP all read their list of formats from here.
+var formats = map[string]format{
+	"env":        {render: ordered(Result.toENV), parse: parseENV, keyName: fixEnvName},
+	"ini":        {render: ordered(Result.toINI), keyName: fixININame},
+	"json":       {render: nestable(ordered(Result.toJSON)), parse: parseJSON, nested: true},
+	"properties": {render: ordered(Result.toProperties), parse: parseProperties},
+	"secret":     {render: ordered(Result.toK8sSecret), parse: parseSecretKV},
+	"template":   {render: nestable(templateFormatter), nested: true},
+	"toml":       {render: ordered(Result.toTOML)},
+	"yaml":       {render: nestable(ordered(Result.toYAML)), parse: parseYAML, nested: true},
+}
+
+// ordered wraps formats that write the keys they are given in that order and cannot fail, so they honor FormatOptions.Order
+func ordered(format func(Result, []string) string) Formatter {
+	return func(result Result, options FormatOptions) (string, error) {
+		return format(result, result.Keys(options.Order)), nil
+	}
+}
+
+// nestable wraps formats that can represent nested structures, so they honor FormatOptions.Nested
+func nestable(format Formatter) Formatter {
+	return func(result Result, options FormatOptions) (string, error) {
+		if options.Nested {
+			nested, err := result.Nest()
+			if err != nil {
+				return "", err
+			}
+			result = nested
+			if options.Order != nil {
+				options.Order = nestedOrder(options.Order)
+			}
+		}
+		return format(result, options)
+	}
+}
+
+// GetParser returns the Parser of the given format name, it returns false for formats that can't be read back
+func GetParser(name string) (Parser, bool) {
+	f := formats[name]
+	return f.parse, f.parse != nil
+}
+
+// GetFormatter returns the Formatter registered for the given format name
+func GetFormatter(name string) (Formatter, bool) {
+	f, ok := formats[name]
+	return f.render, ok
+}
+
+// KeyName returns the name a key is written as in the given format, e.g. db.host is written as db_host in the env format
+func KeyName(name string, key string) string {
+	if f := formats[name]; f.keyName != nil {
+		return f.keyName(key)
+	}
+	return key
+}
+
+// IsFormat reports whether the given format name is registered
+func IsFormat(name string) bool {
+	_, ok := formats[name]
+	return ok
+}
+
+// CanMerge reports whether files of the given format can be read back and merged with new secrets
+func CanMerge(name string) bool {
+	return formats[name].parse != nil
+}
+
+// Formats returns the names of all registered formats in sorted order
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// renameSeparator separates the regular expression of a rename rule from its replacement, e.g. ^spring_(.*)$ -> SPRING_$1
+const renameSeparator = " -> "
+
+// KeySelector leaves out and renames the keys of a secret that is fetched as a whole
+type KeySelector struct {
+	exclude []string
+	rename  []renameRule
+}
+
+type renameRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewKeySelector returns a KeySelector leaving out the keys matching one of the exclude globs,
+// and renaming the other keys with the first of the rename rules that matches them.
+// A rename rule is a regular expression and its replacement separated by " -> ", the replacement can use the groups of the expression as $1 or ${name}.
+func NewKeySelector(exclude []string, rename []string) (*KeySelector, error) {
+	for _, glob := range exclude {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude '%s': %w", glob, err)
+		}
+	}
+
+	rules := make([]renameRule, 0, len(rename))
+	for _, rule := range rename {
+		expression, replacement, found := strings.Cut(rule, renameSeparator)
+		if !found {
+			return nil, fmt.Errorf("invalid rename '%s', expected a regular expression and its replacement separated by '%s'", rule, renameSeparator)
+		}
+		pattern, err := regexp.Compile(strings.TrimSpace(expression))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rename '%s': %w", rule, err)
+		}
+		rules = append(rules, renameRule{pattern: pattern, replacement: strings.TrimSpace(replacement)})
+	}
+	return &KeySelector{exclude: exclude, rename: rules}, nil
+}
+
+// Select returns the keys of the secret that are not excluded, by their new names.
+// Two keys getting the same name is an error, as one of them would be lost.
+func (s *KeySelector) Select(secret map[string]any) (map[string]any, error) {
+	keys := make([]string, 0, len(secret))
+	for key := range secret {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	selected := make(map[string]any, len(secret))
+	renamedFrom := make(map[string]string, len(secret))
+	for _, key := range keys {
+		if s.excluded(key) {
+			continue
+		}
+		name := s.newName(key)
+		if previous, ok := renamedFrom[name]; ok {
+			return nil, fmt.Errorf("the keys '%s' and '%s' are both renamed to '%s'", previous, key, name)
+		}
+		renamedFrom[name] = key
+		selected[name] = secret[key]
+	}
+	return selected, nil
+}
+
+func (s *KeySelector) excluded(key string) bool {
+	for _, glob := range s.exclude {
+		if matched, _ := path.Match(glob, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *KeySelector) newName(key string) string {
+	for _, rule := range s.rename {
+		if rule.pattern.MatchString(key) {
+			return rule.pattern.ReplaceAllString(key, rule.replacement)
+		}
+	}
+	return key
+}
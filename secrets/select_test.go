@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeySelector(t *testing.T) {
+	secret := map[string]any{
+		"spring_datasource_url": "jdbc:postgresql://db",
+		"spring_profile":        "prod",
+		"admin_user":            "root",
+		"admin_password":        "hunter22",
+		"api_key":               "abc123",
+	}
+
+	selector, err := NewKeySelector([]string{"admin_*"}, []string{`^spring_(.*)$ -> SPRING_${1}`, `^(.*)_key$ -> ${1}_token`})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	selected, err := selector.Select(secret)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := map[string]any{
+		"SPRING_datasource_url": "jdbc:postgresql://db",
+		"SPRING_profile":        "prod",
+		"api_token":             "abc123",
+	}
+	if !reflect.DeepEqual(selected, expected) {
+		t.Errorf("expected %v, got %v", expected, selected)
+	}
+}
+
+func TestKeySelectorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		exclude []string
+		rename  []string
+		secret  map[string]any
+		wantErr string
+	}{
+		{"invalid glob", []string{"[admin"}, nil, nil, "invalid exclude '[admin'"},
+		{"missing separator", nil, []string{"^spring_(.*)$"}, nil, "separated by ' -> '"},
+		{"invalid regular expression", nil, []string{"^(spring -> SPRING"}, nil, "invalid rename"},
+		{"renamed to the same key", nil, []string{"^.*$ -> key"}, map[string]any{"a": "1", "b": "2"}, "the keys 'a' and 'b' are both renamed to 'key'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := NewKeySelector(tt.exclude, tt.rename)
+			if err == nil {
+				_, err = selector.Select(tt.secret)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// shells holds how each supported shell sets an environment variable, keyed by the name used with --shell
+var shells = map[string]func(key string, value string) string{
+	"bash":       posixExport,
+	"fish":       fishExport,
+	"nushell":    nushellExport,
+	"powershell": powershellExport,
+	"zsh":        posixExport,
+}
+
+// shellAliases maps executable names to the shell they belong to
+var shellAliases = map[string]string{
+	"nu":             "nushell",
+	"pwsh":           "powershell",
+	"powershell.exe": "powershell",
+	"pwsh.exe":       "powershell",
+	"sh":             "bash",
+}
+
+// Shells returns the names of all supported shells in sorted order
+func Shells() []string {
+	names := make([]string, 0, len(shells))
+	for name := range shells {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DetectShell returns the supported shell matching the path of a shell executable, e.g. the SHELL environment variable.
+// It falls back to bash when the shell is unknown.
+func DetectShell(shellPath string) string {
+	name := strings.ToLower(filepath.Base(shellPath))
+	if alias, ok := shellAliases[name]; ok {
+		return alias
+	}
+	if _, ok := shells[name]; ok {
+		return name
+	}
+	return "bash"
+}
+
+// ToShell exports secrets as commands setting environment variables in the given shell, meant to be evaluated by that shell.
+// The keys are written in the given order like the formats do, a nil order writes them sorted.
+func (result Result) ToShell(shell string, order []string) (string, error) {
+	export, ok := shells[shell]
+	if !ok {
+		return "", fmt.Errorf("unknown shell '%s', please use either: %s", shell, strings.Join(Shells(), ", "))
+	}
+
+	result.warnEnvNameCollisions()
+	var sb strings.Builder
+	for _, key := range result.Keys(order) {
+		sb.WriteString(export(fixEnvName(key), getPlainRepresentation(result[key])))
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// posixExport works for bash, zsh and other POSIX shells, nothing is special inside single quotes except the quote itself
+func posixExport(key string, value string) string {
+	return fmt.Sprintf("export %s='%s'", key, strings.ReplaceAll(value, "'", `'\''`))
+}
+
+// fishExport uses single quotes, where fish only treats \\ and \' as escapes
+func fishExport(key string, value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "'", `\'`)
+	return fmt.Sprintf("set -gx %s '%s';", key, value)
+}
+
+// powershellExport uses single quotes, PowerShell also treats the typographic single quotes as quotes so all of them are doubled
+func powershellExport(key string, value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			sb.WriteRune(r)
+		}
+		sb.WriteRune(r)
+	}
+	return fmt.Sprintf("$env:%s = '%s'", key, sb.String())
+}
+
+// nushellExport uses a raw string, with enough # characters that the value can't end it
+func nushellExport(key string, value string) string {
+	hashes := "#"
+	for strings.Contains(value, "'"+hashes) {
+		hashes += "#"
+	}
+	return fmt.Sprintf("$env.%s = r%s'%s'%s", key, hashes, value, hashes)
+}
@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"testing"
+)
+
+func TestToShell(t *testing.T) {
+	result := Result{"b-key": "it's \\ $HOME\nnext", "a_key": "value"}
+
+	tests := []struct {
+		shell    string
+		expected string
+	}{
+		{"bash", "export a_key='value'\nexport b_key='it'\\''s \\ $HOME\nnext'\n"},
+		{"zsh", "export a_key='value'\nexport b_key='it'\\''s \\ $HOME\nnext'\n"},
+		{"fish", "set -gx a_key 'value';\nset -gx b_key 'it\\'s \\\\ $HOME\nnext';\n"},
+		{"powershell", "$env:a_key = 'value'\n$env:b_key = 'it''s \\ $HOME\nnext'\n"},
+		{"nushell", "$env.a_key = r#'value'#\n$env.b_key = r#'it's \\ $HOME\nnext'#\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			actual, err := result.ToShell(tt.shell, nil)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToShellQuotesEdgeCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		shell    string
+		value    any
+		expected string
+	}{
+		{"powershell typographic quote", "powershell", "a’b", "$env:key = 'a’’b'\n"},
+		{"nushell raw string end", "nushell", "a'#b", "$env.key = r##'a'#b'##\n"},
+		{"bytes", "bash", []byte("binary"), "export key='binary'\n"},
+		{"number", "fish", float64(5432), "set -gx key '5432';\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := Result{"key": tt.value}.ToShell(tt.shell, nil)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToShellOrder(t *testing.T) {
+	result := Result{"b": "2", "a": "1", "c": "3"}
+	expected := "export c='3'\nexport a='1'\nexport b='2'\n"
+
+	actual, err := result.ToShell("bash", []string{"c", "a"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestToShellUnknownShell(t *testing.T) {
+	if _, err := (Result{}).ToShell("cmd", nil); err == nil {
+		t.Errorf("expected an error for an unknown shell")
+	}
+}
+
+func TestDetectShell(t *testing.T) {
+	tests := map[string]string{
+		"/bin/zsh":            "zsh",
+		"/usr/bin/fish":       "fish",
+		"/usr/local/bin/pwsh": "powershell",
+		"/usr/bin/nu":         "nushell",
+		"":                    "bash",
+		"/bin/tcsh":           "bash",
+	}
+
+	for shellPath, expected := range tests {
+		if actual := DetectShell(shellPath); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, shellPath, actual)
+		}
+	}
+}
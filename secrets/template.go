@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/BESTSELLER/harpocrates/files"
+	"github.com/rs/zerolog/log"
+)
+
+// templateFuncs are the helper functions available in templates, named after their sprig counterparts
+var templateFuncs = template.FuncMap{
+	"b64enc": func(value any) string {
+		return base64.StdEncoding.EncodeToString([]byte(getPlainRepresentation(value)))
+	},
+	"b64dec": func(value any) (string, error) {
+		decoded, err := base64.StdEncoding.DecodeString(getPlainRepresentation(value))
+		if err != nil {
+			return "", fmt.Errorf("unable to base64 decode value: %w", err)
+		}
+		return string(decoded), nil
+	},
+	"toJson": func(value any) (string, error) {
+		jsonBytes, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("unable to convert value to json: %w", err)
+		}
+		return string(jsonBytes), nil
+	},
+	"indent": func(spaces int, value any) string {
+		padding := strings.Repeat(" ", spaces)
+		return padding + strings.ReplaceAll(getPlainRepresentation(value), "\n", "\n"+padding)
+	},
+	"default": func(defaultValue any, value any) any {
+		if value == nil || value == "" {
+			return defaultValue
+		}
+		return value
+	},
+	"required": func(message string, value any) (any, error) {
+		if value == nil || value == "" {
+			return nil, errors.New(message)
+		}
+		return value, nil
+	},
+}
+
+// templateFormatter reads the template file from the options and renders the result with it
+func templateFormatter(result Result, options FormatOptions) (string, error) {
+	if options.Template == "" {
+		return "", fmt.Errorf("the template format requires a template file")
+	}
+
+	text, err := files.Read(options.Template)
+	if err != nil {
+		return "", err
+	}
+
+	return result.ToTemplate(filepath.Base(options.Template), text)
+}
+
+// ToTemplate renders the result with the given Go text/template.
+// Secrets are available as fields on the root object, e.g. {{ .KEY }} or {{ index . "some.key" }}, and referencing a missing key fails.
+// get "KEY" gives nil for a missing key instead, so default and required can handle it.
+func (result Result) ToTemplate(name string, text string) (string, error) {
+	log.Debug().Msgf("Exporting with template '%s'", name)
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Funcs(template.FuncMap{
+		"get": func(key string) any {
+			return result[key]
+		},
+	}).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse template '%s': %w", name, err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, map[string]any(result)); err != nil {
+		return "", fmt.Errorf("unable to render template '%s': %w", name, err)
+	}
+	return sb.String(), nil
+}
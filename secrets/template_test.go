@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"testing"
+)
+
+func TestToTemplate(t *testing.T) {
+	result := Result{"USER": "app", "PASSWORD": "s3cr3t", "db.host": "localhost", "CERT": "line1\nline2", "EMPTY": ""}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"fields", "jdbc:postgresql://{{ index . \"db.host\" }}/app?user={{ .USER }}&password={{ .PASSWORD }}", "jdbc:postgresql://localhost/app?user=app&password=s3cr3t"},
+		{"b64enc", "{{ .PASSWORD | b64enc }}", "czNjcjN0"},
+		{"b64dec", "{{ \"czNjcjN0\" | b64dec }}", "s3cr3t"},
+		{"toJson", "{{ .CERT | toJson }}", "\"line1\\nline2\""},
+		{"indent", "cert: |\n{{ .CERT | indent 2 }}", "cert: |\n  line1\n  line2"},
+		{"default", "{{ .EMPTY | default \"fallback\" }}", "fallback"},
+		{"get", "{{ get \"db.host\" }}", "localhost"},
+		{"default missing", "{{ get \"MISSING\" | default \"fallback\" }}", "fallback"},
+		{"required", "{{ .USER | required \"USER is required\" }}", "app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := result.ToTemplate(tt.name, tt.template)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToTemplateErrors(t *testing.T) {
+	result := Result{"EMPTY": ""}
+
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"missing key", "{{ .MISSING }}"},
+		{"missing key with default", "{{ .MISSING | default \"fallback\" }}"},
+		{"required missing", "{{ get \"MISSING\" | required \"MISSING is required\" }}"},
+		{"required", "{{ .EMPTY | required \"EMPTY is required\" }}"},
+		{"parse error", "{{ .EMPTY "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := result.ToTemplate(tt.name, tt.template); err == nil {
+				t.Errorf("expected an error for template %q", tt.template)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// transformation is a single step of a transform pipeline
+type transformation struct {
+	// apply changes the value, arg is only set for steps that take an argument
+	apply func(value any, arg string) (any, error)
+	// hasArg marks steps that are written as an object, e.g. {"json-extract": "db.username"}
+	hasArg bool
+}
+
+// transformations holds every step that can be used in a transform list.
+// The JSON schema reads its list of steps from here.
+var transformations = map[string]transformation{
+	"base64decode": {apply: base64Decode},
+	"base64encode": {apply: onString(func(text string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(text)), nil
+	})},
+	"trim": {apply: onString(func(text string) (string, error) {
+		return strings.TrimSpace(text), nil
+	})},
+	"lower": {apply: onString(func(text string) (string, error) {
+		return strings.ToLower(text), nil
+	})},
+	"upper": {apply: onString(func(text string) (string, error) {
+		return strings.ToUpper(text), nil
+	})},
+	"unescape-newlines": {apply: onString(func(text string) (string, error) {
+		return strings.NewReplacer(`\r\n`, "\r\n", `\n`, "\n", `\r`, "\r").Replace(text), nil
+	})},
+	"json-extract": {apply: jsonExtract, hasArg: true},
+}
+
+// onString lets a step work on the plain text of a value
+func onString(step func(text string) (string, error)) func(value any, arg string) (any, error) {
+	return func(value any, _ string) (any, error) {
+		return step(getPlainRepresentation(value))
+	}
+}
+
+// base64Decode accepts both padded and unpadded input, line breaks as in PEM wrapped data are ignored.
+// The decoded value is kept as bytes, so binary content such as a keystore is written byte-exact.
+func base64Decode(value any, _ string) (any, error) {
+	text := getPlainRepresentation(value)
+	text = strings.Join(strings.Fields(text), "")
+
+	decoded, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(text, "="))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("value is not valid base64: %w", err)
+	}
+	return decoded, nil
+}
+
+// AsText turns the bytes of a decoded value into a string, so it is written as text by every format.
+// Only values saved as their own file are kept as bytes.
+func AsText(value any) any {
+	if decoded, isBytes := value.([]byte); isBytes {
+		return string(decoded)
+	}
+	return value
+}
+
+// jsonExtract reads a value from a JSON object, arg uses the same syntax as nested keys
+func jsonExtract(value any, arg string) (any, error) {
+	object, isMap := value.(map[string]any)
+	if !isMap {
+		if err := json.Unmarshal([]byte(getPlainRepresentation(value)), &object); err != nil {
+			return nil, fmt.Errorf("value is not a json object: %w", err)
+		}
+	}
+
+	extracted, found := Lookup(object, arg)
+	if !found {
+		return nil, fmt.Errorf("the key '%s' was not found in the json value", arg)
+	}
+	return extracted, nil
+}
+
+// Transform runs value through the given steps in order.
+// A step is either the name of a transformation, or an object with a single name and argument.
+func Transform(value any, steps []any) (any, error) {
+	for _, step := range steps {
+		name, arg, err := parseTransformStep(step)
+		if err != nil {
+			return nil, err
+		}
+
+		transform, ok := transformations[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transform '%s'", name)
+		}
+		if transform.hasArg && arg == "" {
+			return nil, fmt.Errorf("the transform '%s' requires an argument", name)
+		}
+
+		value, err = transform.apply(value, arg)
+		if err != nil {
+			return nil, fmt.Errorf("transform '%s' failed: %w", name, err)
+		}
+	}
+	return value, nil
+}
+
+func parseTransformStep(step any) (string, string, error) {
+	switch s := step.(type) {
+	case string:
+		return s, "", nil
+	case map[string]any:
+		if len(s) == 1 {
+			for name, arg := range s {
+				return name, fmt.Sprintf("%v", arg), nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("expected a transform name or an object with a single transform, got %v", step)
+}
+
+// TransformNames returns the names of all transformations written as plain strings, in sorted order
+func TransformNames() []string {
+	return transformNames(false)
+}
+
+// TransformNamesWithArgument returns the names of all transformations that take an argument, in sorted order
+func TransformNamesWithArgument() []string {
+	return transformNames(true)
+}
+
+func transformNames(hasArg bool) []string {
+	var names []string
+	for name, transform := range transformations {
+		if transform.hasArg == hasArg {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		steps    []any
+		expected any
+	}{
+		{"no steps", "value", nil, "value"},
+		{"base64decode", "aGVsbG8=", []any{"base64decode"}, []byte("hello")},
+		{"base64decode unpadded and wrapped", "aGVs\nbG8", []any{"base64decode"}, []byte("hello")},
+		{"base64decode binary", "AAH/", []any{"base64decode"}, []byte{0x00, 0x01, 0xff}},
+		{"base64encode", "hello", []any{"base64encode"}, "aGVsbG8="},
+		{"trim lower", "  HeLLo \n", []any{"trim", "lower"}, "hello"},
+		{"upper", "hello", []any{"upper"}, "HELLO"},
+		{"unescape-newlines", `-----BEGIN-----\nabc\r\n-----END-----`, []any{"unescape-newlines"}, "-----BEGIN-----\nabc\r\n-----END-----"},
+		{"json-extract from string", `{"db":{"users":[{"name":"app"}]}}`, []any{map[string]any{"json-extract": "db.users[0].name"}}, "app"},
+		{"json-extract from object", map[string]any{"password": "s3cr3t"}, []any{map[string]any{"json-extract": "password"}}, "s3cr3t"},
+		{"pipeline", "eyJwYXNzd29yZCI6IiBzM2NyM3QgIn0=", []any{"base64decode", map[string]any{"json-extract": "password"}, "trim"}, "s3cr3t"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := Transform(tt.value, tt.steps)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		steps []any
+	}{
+		{"unknown step", "value", []any{"reverse"}},
+		{"invalid base64", "not base64!", []any{"base64decode"}},
+		{"json-extract without argument", `{"a":"b"}`, []any{"json-extract"}},
+		{"json-extract missing key", `{"a":"b"}`, []any{map[string]any{"json-extract": "c"}}},
+		{"json-extract not json", "value", []any{map[string]any{"json-extract": "a"}}},
+		{"object with two steps", "value", []any{map[string]any{"trim": "", "lower": ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Transform(tt.value, tt.steps); err == nil {
+				t.Errorf("expected an error for steps %v", tt.steps)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package secrets
+
+// Values returns the text of every value, as it can show up in the output of an application.
+// Nested objects give the values inside them as well as the object itself as JSON.
+func (result Result) Values() []string {
+	var values []string
+	for _, value := range result {
+		values = appendValues(values, value)
+	}
+	return values
+}
+
+func appendValues(values []string, value any) []string {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, child := range v {
+			values = appendValues(values, child)
+		}
+	case []any:
+		for _, child := range v {
+			values = appendValues(values, child)
+		}
+	}
+	return append(values, getPlainRepresentation(value))
+}
@@ -0,0 +1,18 @@
+package secrets
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	result := Result{"password": "s3cr3t", "port": float64(5432), "db": map[string]any{"user": "app"}, "cert": []byte("pem")}
+
+	values := result.Values()
+	slices.Sort(values)
+
+	expected := []string{"5432", "app", "pem", "s3cr3t", `{"user":"app"}`}
+	if !slices.Equal(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package util
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Exec replaces the current process with the given command, it only returns if that fails.
+// The command keeps the process ID, so it receives signals directly and its exit code is the exit code of the process.
+// Later entries in env override earlier ones with the same name, like with exec.Cmd.
+func Exec(command []string, env []string) error {
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, command, dedupEnv(env))
+}
+
+// dedupEnv keeps the last value of every variable, in the position it was first seen
+func dedupEnv(env []string) []string {
+	index := map[string]int{}
+	deduped := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[name]; ok {
+			deduped[i] = kv
+			continue
+		}
+		index[name] = len(deduped)
+		deduped = append(deduped, kv)
+	}
+	return deduped
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package util
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDedupEnv(t *testing.T) {
+	env := []string{"PATH=/bin", "PASSWORD=old", "HOME=/root", "PASSWORD=new"}
+
+	expected := []string{"PATH=/bin", "PASSWORD=new", "HOME=/root"}
+	if actual := dedupEnv(env); !slices.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
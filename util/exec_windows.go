@@ -0,0 +1,29 @@
+//go:build windows
+
+package util
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// Exec runs the given command and exits with its exit code, Windows can't replace the current process.
+// Ctrl+C is delivered to the whole console, so it is ignored here and left to the command.
+func Exec(command []string, env []string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	signal.Ignore(os.Interrupt)
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,133 @@
+package util
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ResolveIncludes merges the specs listed in include into the spec, following their includes too.
+// specFile is where the spec was read from, relative includes are relative to its directory, or to the working directory for an inline spec.
+//
+// Included specs are merged in the order they are listed, so a later include takes precedence over an earlier one,
+// and the including spec takes precedence over all of them. Their secrets are fetched before the secrets of the including spec.
+func (secretJSON SecretJSON) ResolveIncludes(specFile string) (SecretJSON, error) {
+	return secretJSON.resolveIncludes(specFile, nil)
+}
+
+func (secretJSON SecretJSON) resolveIncludes(specFile string, chain []string) (SecretJSON, error) {
+	if len(secretJSON.Include) == 0 {
+		return secretJSON, nil
+	}
+
+	chain, err := IncludeChain(chain, specFile)
+	if err != nil {
+		return secretJSON, err
+	}
+
+	merged := SecretJSON{}
+	for _, include := range secretJSON.Include {
+		path := IncludePath(specFile, include)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return secretJSON, fmt.Errorf("unable to include '%s': %w", include, err)
+		}
+		included, err := parseSpec(string(data))
+		if err != nil {
+			return secretJSON, fmt.Errorf("unable to include '%s': %w", path, err)
+		}
+		included, err = included.resolveIncludes(path, chain)
+		if err != nil {
+			return secretJSON, err
+		}
+		merged = merged.mergedWith(included)
+		merged.IncludedFiles = append(merged.IncludedFiles, path)
+	}
+
+	secretJSON.Include = nil
+	return merged.mergedWith(secretJSON), nil
+}
+
+// IncludePath returns the path of an included spec, relative paths are relative to the directory of the including specFile
+func IncludePath(specFile string, include string) string {
+	if specFile == "" || filepath.IsAbs(include) {
+		return include
+	}
+	return filepath.Join(filepath.Dir(specFile), include)
+}
+
+// IncludeChain adds specFile to the chain of specs including each other, and returns an error if it is already in it as the includes are a cycle
+func IncludeChain(chain []string, specFile string) ([]string, error) {
+	if specFile == "" {
+		return chain, nil
+	}
+	absPath, err := filepath.Abs(specFile)
+	if err != nil {
+		return chain, err
+	}
+	if idx := slices.Index(chain, absPath); idx != -1 {
+		return chain, fmt.Errorf("include cycle: %s", strings.Join(append(slices.Clone(chain[idx:]), absPath), " -> "))
+	}
+	return append(slices.Clone(chain), absPath), nil
+}
+
+// mergedWith returns the spec with the options set in override replacing its own, and the secrets of override after its own
+func (secretJSON SecretJSON) mergedWith(override SecretJSON) SecretJSON {
+	merged := secretJSON
+	if override.Append != nil {
+		merged.Append = override.Append
+	}
+	if override.Conflict != "" {
+		merged.Conflict = override.Conflict
+	}
+	if override.Duplicates != "" {
+		merged.Duplicates = override.Duplicates
+	}
+	if override.DefaultProfile != "" {
+		merged.DefaultProfile = override.DefaultProfile
+	}
+	if override.Format != "" {
+		merged.Format = override.Format
+	}
+	if override.Nested != nil {
+		merged.Nested = override.Nested
+	}
+	if override.Order != "" {
+		merged.Order = override.Order
+	}
+	if override.Output != "" {
+		merged.Output = override.Output
+	}
+	if override.Owner != nil {
+		merged.Owner = override.Owner
+	}
+	if override.Prefix != "" {
+		merged.Prefix = override.Prefix
+	}
+	if override.UpperCase != nil {
+		merged.UpperCase = override.UpperCase
+	}
+	if override.SymlinkSwap != nil {
+		merged.SymlinkSwap = override.SymlinkSwap
+	}
+	if override.Template != "" {
+		merged.Template = override.Template
+	}
+	merged.GcpWorkloadID = merged.GcpWorkloadID || override.GcpWorkloadID
+
+	// Profiles with the same name are replaced as a whole
+	if len(override.Profiles) > 0 {
+		merged.Profiles = maps.Clone(merged.Profiles)
+		if merged.Profiles == nil {
+			merged.Profiles = map[string]Profile{}
+		}
+		maps.Copy(merged.Profiles, override.Profiles)
+	}
+
+	merged.Secrets = append(slices.Clone(merged.Secrets), override.Secrets...)
+	merged.IncludedFiles = append(slices.Clone(merged.IncludedFiles), override.IncludedFiles...)
+	return merged
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSpecs(t *testing.T, specs map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range specs {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolveIncludes(t *testing.T) {
+	dir := writeSpecs(t, map[string]string{
+		"shared/platform.yaml": "format: json\nprefix: PLATFORM_\ninclude:\n  - sentry.yaml\nsecrets:\n  - secret/data/platform/datadog\n",
+		"shared/sentry.yaml":   "format: yaml\noutput: /shared\nsecrets:\n  - secret/data/platform/sentry\n",
+		"app.yaml":             "include:\n  - shared/platform.yaml\nformat: env\nsecrets:\n  - secret/data/team/app\n",
+	})
+	specFile := filepath.Join(dir, "app.yaml")
+	data, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := parseSpec(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err = spec.ResolveIncludes(specFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if spec.Format != "env" || spec.Prefix != "PLATFORM_" || spec.Output != "/shared" {
+		t.Errorf("expected the including spec to take precedence, got format %q, prefix %q and output %q", spec.Format, spec.Prefix, spec.Output)
+	}
+	expectedSecrets := []any{"secret/data/platform/sentry", "secret/data/platform/datadog", "secret/data/team/app"}
+	if !reflect.DeepEqual(spec.Secrets, expectedSecrets) {
+		t.Errorf("expected secrets %v, got %v", expectedSecrets, spec.Secrets)
+	}
+	expectedFiles := []string{filepath.Join(dir, "shared/sentry.yaml"), filepath.Join(dir, "shared/platform.yaml")}
+	if !reflect.DeepEqual(spec.IncludedFiles, expectedFiles) {
+		t.Errorf("expected included files %v, got %v", expectedFiles, spec.IncludedFiles)
+	}
+	if spec.Include != nil {
+		t.Errorf("expected include to be cleared, got %v", spec.Include)
+	}
+}
+
+func TestResolveIncludesErrors(t *testing.T) {
+	dir := writeSpecs(t, map[string]string{
+		"a.yaml":       "include:\n  - b.yaml\nsecrets:\n  - secret/data/a\n",
+		"b.yaml":       "include:\n  - a.yaml\nsecrets:\n  - secret/data/b\n",
+		"missing.yaml": "include:\n  - nowhere.yaml\n",
+	})
+
+	tests := []struct {
+		name     string
+		specFile string
+		contains string
+	}{
+		{"cycle", "a.yaml", "include cycle: " + filepath.Join(dir, "a.yaml") + " -> " + filepath.Join(dir, "b.yaml") + " -> " + filepath.Join(dir, "a.yaml")},
+		{"missing file", "missing.yaml", "unable to include 'nowhere.yaml'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specFile := filepath.Join(dir, tt.specFile)
+			data, err := os.ReadFile(specFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec, err := parseSpec(string(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = spec.ResolveIncludes(specFile)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("expected an error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// variableRegexp matches a ${name} or ${name:-default} reference to a variable
+var variableRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// ExpandEnv fills in ${VAR} and ${VAR:-default} from the environment in the secret paths, prefixes, output and filenames of the spec.
+// The default is used when the variable is unset or empty. Undefined variables without a default are left as they are,
+// unless strict is set, in which case they are an error.
+func (secretJSON SecretJSON) ExpandEnv(strict bool) (SecretJSON, error) {
+	e := &expander{lookup: lookupEnv, useDefaults: true}
+	secretJSON = e.expandSpec(secretJSON)
+
+	if len(e.undefined) > 0 {
+		if strict {
+			return secretJSON, fmt.Errorf("undefined variables: %s", strings.Join(e.undefined, ", "))
+		}
+		log.Warn().Msgf("Undefined variables are left as they are: %s", strings.Join(e.undefined, ", "))
+	}
+	return secretJSON, nil
+}
+
+// lookupEnv looks up an environment variable, treating an empty value as unset like ${VAR:-default} does in a shell
+func lookupEnv(name string) (string, bool) {
+	value, ok := os.LookupEnv(name)
+	return value, ok && value != ""
+}
+
+// expander fills in variables in the parts of a spec that may hold them
+type expander struct {
+	lookup func(name string) (string, bool)
+	// useDefaults uses the default of ${name:-default} when the variable is not found, otherwise the reference is left for a later expander
+	useDefaults bool
+	// undefined are the variables that were not found and had no default, in the order they were found
+	undefined []string
+}
+
+func (e *expander) expandSpec(secretJSON SecretJSON) SecretJSON {
+	secretJSON.Output = e.expand(secretJSON.Output)
+	secretJSON.Prefix = e.expand(secretJSON.Prefix)
+	secretJSON.Secrets = e.expandEntries(secretJSON.Secrets, e.expandSecretConfig)
+	return secretJSON
+}
+
+// expandEntries expands entries that are either a string or a map of a name to its configuration
+func (e *expander) expandEntries(entries []any, expandConfig func(any) any) []any {
+	expanded := make([]any, len(entries))
+	for i, entry := range entries {
+		switch entry := entry.(type) {
+		case string:
+			expanded[i] = e.expand(entry)
+		case map[string]any:
+			expandedEntry := make(map[string]any, len(entry))
+			for name, entryConfig := range entry {
+				expandedEntry[e.expand(name)] = expandConfig(entryConfig)
+			}
+			expanded[i] = expandedEntry
+		default:
+			expanded[i] = entry
+		}
+	}
+	return expanded
+}
+
+func (e *expander) expandSecretConfig(secretConfig any) any {
+	expanded := e.expandConfig(secretConfig)
+	if configMap, ok := expanded.(map[string]any); ok {
+		if keys, ok := configMap["keys"].([]any); ok {
+			configMap["keys"] = e.expandEntries(keys, e.expandConfig)
+		}
+	}
+	return expanded
+}
+
+// expandConfig expands the prefix and filename of a secret or key configuration
+func (e *expander) expandConfig(entryConfig any) any {
+	configMap, ok := entryConfig.(map[string]any)
+	if !ok {
+		return entryConfig
+	}
+
+	expanded := make(map[string]any, len(configMap))
+	for option, value := range configMap {
+		if text, ok := value.(string); ok && (option == "prefix" || option == "filename") {
+			value = e.expand(text)
+		}
+		expanded[option] = value
+	}
+	return expanded
+}
+
+// expand replaces the variables in text
+func (e *expander) expand(text string) string {
+	return variableRegexp.ReplaceAllStringFunc(text, func(reference string) string {
+		match := variableRegexp.FindStringSubmatch(reference)
+		if value, ok := e.lookup(match[1]); ok {
+			return value
+		}
+		if !e.useDefaults {
+			return reference
+		}
+		if strings.Contains(reference, ":-") {
+			return match[2]
+		}
+		if !slices.Contains(e.undefined, match[1]) {
+			e.undefined = append(e.undefined, match[1])
+		}
+		return reference
+	})
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("NAMESPACE", "payments")
+	t.Setenv("CLUSTER", "")
+
+	spec := SecretJSON{
+		Output: "/secrets/${NAMESPACE}",
+		Prefix: "${CLUSTER:-LOCAL}_",
+		Secrets: []any{
+			"secret/data/${NAMESPACE}/config",
+			map[string]any{
+				"secret/data/${CLUSTER:-dev}/tls": map[string]any{
+					"prefix": "${NAMESPACE}_",
+					"keys": []any{
+						"ca",
+						map[string]any{"cert": map[string]any{"saveAsFile": true, "filename": "${NAMESPACE}.crt"}},
+					},
+				},
+			},
+		},
+	}
+
+	expanded, err := spec.ExpandEnv(true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := SecretJSON{
+		Output: "/secrets/payments",
+		Prefix: "LOCAL_",
+		Secrets: []any{
+			"secret/data/payments/config",
+			map[string]any{
+				"secret/data/dev/tls": map[string]any{
+					"prefix": "payments_",
+					"keys": []any{
+						"ca",
+						map[string]any{"cert": map[string]any{"saveAsFile": true, "filename": "payments.crt"}},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(expanded, expected) {
+		t.Errorf("expected %v, got %v", expected, expanded)
+	}
+}
+
+func TestExpandEnvUndefined(t *testing.T) {
+	spec := SecretJSON{Secrets: []any{"secret/data/${HARPOCRATES_TEST_UNDEFINED}/config"}}
+
+	expanded, err := spec.ExpandEnv(false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(expanded.Secrets, spec.Secrets) {
+		t.Errorf("expected undefined variables to be left as they are, got %v", expanded.Secrets)
+	}
+
+	_, err = spec.ExpandEnv(true)
+	if err == nil || !strings.Contains(err.Error(), "HARPOCRATES_TEST_UNDEFINED") {
+		t.Errorf("expected an error naming the undefined variable, got %v", err)
+	}
+}
+
+func TestApplyProfileBeforeExpandEnv(t *testing.T) {
+	t.Setenv("team", "from-env")
+
+	spec := SecretJSON{
+		Profiles: map[string]Profile{"dev": {Variables: map[string]string{"team": "platform"}}},
+		Secrets:  []any{"secret/data/${team}/${region:-europe}/${profile}"},
+	}
+	spec, err := spec.ApplyProfile("dev")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	spec, err = spec.ExpandEnv(true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if expected := []any{"secret/data/platform/europe/dev"}; !reflect.DeepEqual(spec.Secrets, expected) {
+		t.Errorf("expected %v, got %v", expected, spec.Secrets)
+	}
+}
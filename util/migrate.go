@@ -0,0 +1,264 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// migration upgrades a spec from one apiVersion to the next
+type migration struct {
+	from  string
+	to    string
+	apply func(root *yaml.Node) error
+}
+
+// migrations are applied in order until the spec is at LatestSpecVersion
+var migrations = []migration{
+	{from: SpecVersionV1, to: SpecVersionV2, apply: migrateV1ToV2},
+}
+
+// MigrateSpec rewrites a spec in either json or yaml to LatestSpecVersion and returns it along with the version it was in.
+// Comments, blank lines and the order of the options are kept. A spec that is already at LatestSpecVersion is returned as is.
+func MigrateSpec(input string) (string, string, error) {
+	version, err := SpecVersion(input)
+	if err != nil {
+		return "", "", err
+	}
+	if version == LatestSpecVersion {
+		return input, version, nil
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(input), &document); err != nil {
+		return "", version, err
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return "", version, fmt.Errorf("the spec must be an object")
+	}
+	root := document.Content[0]
+
+	current := version
+	for _, step := range migrations {
+		if step.from != current {
+			continue
+		}
+		if err := step.apply(root); err != nil {
+			return "", version, fmt.Errorf("unable to migrate from %s to %s: %w", step.from, step.to, err)
+		}
+		current = step.to
+	}
+	setAPIVersion(root, current)
+
+	var migrated string
+	if json.Valid([]byte(input)) {
+		migrated, err = encodeJSON(root)
+	} else if migrated, err = encodeYAML(&document); err == nil {
+		migrated = keepBlankLines(input, &document, migrated)
+	}
+	return migrated, version, err
+}
+
+// migrateV1ToV2 renames the fileName alias of the secrets to filename
+func migrateV1ToV2(root *yaml.Node) error {
+	secrets := mappingValue(root, "secrets")
+	if secrets == nil || secrets.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range secrets.Content {
+		if entry.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(entry.Content); i += 2 {
+			secretPath, secretConfig := entry.Content[i].Value, entry.Content[i+1]
+			alias := mappingKey(secretConfig, "fileName")
+			if alias == nil {
+				continue
+			}
+			if mappingKey(secretConfig, "filename") != nil {
+				return fmt.Errorf("the secret '%s' sets both filename and fileName, please remove one of them", secretPath)
+			}
+			alias.Value = "filename"
+		}
+	}
+	return nil
+}
+
+// setAPIVersion sets the apiVersion of the spec, adding it as the first option if it is missing.
+// A comment above the first option stays on top of the spec.
+func setAPIVersion(root *yaml.Node, version string) {
+	if value := mappingValue(root, "apiVersion"); value != nil {
+		value.Value = version
+		value.Tag = "!!str"
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "apiVersion"}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: version}
+	if len(root.Content) > 0 {
+		key.HeadComment = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// mappingKey returns the key node of name in a mapping, or nil if it is not there
+func mappingKey(mapping *yaml.Node, name string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return mapping.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value node of name in a mapping, or nil if it is not there
+func mappingValue(mapping *yaml.Node, name string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// encodeYAML writes a document as yaml with its comments
+func encodeYAML(document *yaml.Node) (string, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// keepBlankLines adds back the blank lines the encoder drops, before the same options and items as in the original spec.
+// The migrated document still has the lines of the original, and the encoded spec is read again to find where they ended up.
+func keepBlankLines(original string, migrated *yaml.Node, encoded string) string {
+	var reparsed yaml.Node
+	if err := yaml.Unmarshal([]byte(encoded), &reparsed); err != nil {
+		return encoded
+	}
+	originalLines := strings.Split(original, "\n")
+	encodedLines := strings.Split(encoded, "\n")
+
+	blankBefore := map[int]bool{}
+	var walk func(before *yaml.Node, after *yaml.Node)
+	walk = func(before *yaml.Node, after *yaml.Node) {
+		if before.Kind != after.Kind || len(before.Content) != len(after.Content) {
+			return
+		}
+		for i := range before.Content {
+			isEntry := before.Kind == yaml.SequenceNode || (before.Kind == yaml.MappingNode && i%2 == 0)
+			// An option added on top, like the apiVersion, goes between the blank line and the option that was first
+			addedOnTop := before.Kind == yaml.MappingNode && i == 2 && before.Content[0].Line == 0
+			if isEntry && !addedOnTop && before.Content[i].Line > 0 && blankAbove(originalLines, before.Content[i].Line) {
+				blankBefore[commentStart(encodedLines, after.Content[i].Line)] = true
+			}
+			walk(before.Content[i], after.Content[i])
+		}
+	}
+	walk(migrated, &reparsed)
+
+	var out []string
+	for i, line := range encodedLines {
+		if blankBefore[i] && i > 0 && strings.TrimSpace(encodedLines[i-1]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// blankAbove reports whether there is a blank line above the 1-based line, or above the comments right above it
+func blankAbove(lines []string, line int) bool {
+	for i := line - 2; i >= 0 && i < len(lines); i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			return true
+		}
+		if !strings.HasPrefix(trimmed, "#") {
+			return false
+		}
+	}
+	return false
+}
+
+// commentStart returns the 0-based index of the 1-based line, or of the first of the comments right above it
+func commentStart(lines []string, line int) int {
+	start := line - 1
+	for start > 0 && start <= len(lines) && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "#") {
+		start--
+	}
+	return start
+}
+
+// encodeJSON writes a node as indented json, keeping the order of the options
+func encodeJSON(node *yaml.Node) (string, error) {
+	var compact bytes.Buffer
+	if err := writeJSON(&compact, node); err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+		return "", err
+	}
+	out.WriteString("\n")
+	return out.String(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		buf.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			buf.Write(key)
+			buf.WriteString(":")
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+	case yaml.SequenceNode:
+		buf.WriteString("[")
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		scalar, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(scalar)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrateSpec(t *testing.T) {
+	input := `# The secrets of the app
+format: env # written as env
+secrets:
+  # the whole secret
+  - secret/data/app:
+      fileName: app.env
+  - secret/data/shared:
+      keys:
+        - datadog_api_key
+`
+	expected := `# The secrets of the app
+apiVersion: v2
+format: env # written as env
+secrets:
+  # the whole secret
+  - secret/data/app:
+      filename: app.env
+  - secret/data/shared:
+      keys:
+        - datadog_api_key
+`
+
+	migrated, version, err := MigrateSpec(input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if version != SpecVersionV1 {
+		t.Errorf("expected the spec to be %s, got %s", SpecVersionV1, version)
+	}
+	if migrated != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, migrated)
+	}
+
+	again, version, err := MigrateSpec(migrated)
+	if err != nil || version != LatestSpecVersion || again != migrated {
+		t.Errorf("expected a migrated spec to be left as is, got %q at %s (%v)", again, version, err)
+	}
+}
+
+func TestMigrateSpecKeepsBlankLines(t *testing.T) {
+	input := `# The secrets of the app
+
+format: env
+
+# the secrets
+secrets:
+  - secret/data/app:
+      fileName: app.env
+
+  - secret/data/shared
+`
+	expected := `# The secrets of the app
+
+apiVersion: v2
+format: env
+
+# the secrets
+secrets:
+  - secret/data/app:
+      filename: app.env
+
+  - secret/data/shared
+`
+
+	migrated, _, err := MigrateSpec(input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if migrated != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, migrated)
+	}
+}
+
+func TestMigrateSpecJSON(t *testing.T) {
+	input := `{"secrets": [{"secret/data/app": {"fileName": "app.env", "optional": true}}], "format": "json"}`
+	expected := `{
+  "apiVersion": "v2",
+  "secrets": [
+    {
+      "secret/data/app": {
+        "filename": "app.env",
+        "optional": true
+      }
+    }
+  ],
+  "format": "json"
+}
+`
+
+	migrated, _, err := MigrateSpec(input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if migrated != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, migrated)
+	}
+}
+
+func TestMigrateSpecErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown version": "apiVersion: v0\nsecrets:\n  - secret/data/app\n",
+		"both filenames":  "secrets:\n  - secret/data/app:\n      fileName: a.env\n      filename: b.env\n",
+		"not an object":   "- secret/data/app\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := MigrateSpec(input); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestSpecVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  string
+	}{
+		{"secrets:\n  - secret/data/app\n", SpecVersionV1, ""},
+		{"apiVersion: v1\nsecrets:\n  - secret/data/app\n", SpecVersionV1, ""},
+		{`{"apiVersion": "v2", "secrets": ["secret/data/app"]}`, SpecVersionV2, ""},
+		{"apiVersion: v9\n", "", "unknown apiVersion 'v9'"},
+	}
+
+	for _, tt := range tests {
+		version, err := SpecVersion(tt.input)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || version != tt.expected {
+			t.Errorf("expected %s, got %s (%v)", tt.expected, version, err)
+		}
+	}
+
+	if _, err := parseSpec("apiVersion: v9\nsecrets:\n  - secret/data/app\n"); err == nil {
+		t.Error("expected parsing a spec with an unknown apiVersion to fail")
+	}
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Profile holds the variables and overrides for one environment in a spec, e.g. dev or prod.
+// Options set in a profile replace the ones set on the root of the spec.
+type Profile struct {
+	Variables   map[string]string `json:"variables,omitempty"   yaml:"variables,omitempty"`
+	Append      *bool             `json:"append,omitempty"      yaml:"append,omitempty"`
+	Conflict    string            `json:"conflict,omitempty"    yaml:"conflict,omitempty"`
+	Duplicates  string            `json:"duplicates,omitempty"  yaml:"duplicates,omitempty"`
+	Format      string            `json:"format,omitempty"      yaml:"format,omitempty"`
+	Nested      *bool             `json:"nested,omitempty"      yaml:"nested,omitempty"`
+	Order       string            `json:"order,omitempty"       yaml:"order,omitempty"`
+	Output      string            `json:"output,omitempty"      yaml:"output,omitempty"`
+	Owner       *int              `json:"owner,omitempty"       yaml:"owner,omitempty"`
+	Prefix      string            `json:"prefix,omitempty"      yaml:"prefix,omitempty"`
+	UpperCase   *bool             `json:"uppercase,omitempty"   yaml:"uppercase,omitempty"`
+	SymlinkSwap *bool             `json:"symlinkSwap,omitempty" yaml:"symlinkSwap,omitempty"`
+	Template    string            `json:"template,omitempty"    yaml:"template,omitempty"`
+}
+
+// ApplyProfile selects a profile of the spec, applies its overrides and fills in its variables in the secret paths, prefixes, output and filenames.
+// An empty name selects the defaultProfile of the spec. A spec without profiles is returned as is.
+func (secretJSON SecretJSON) ApplyProfile(name string) (SecretJSON, error) {
+	if len(secretJSON.Profiles) == 0 {
+		if name != "" {
+			return secretJSON, fmt.Errorf("the profile '%s' was selected, but the spec has no profiles", name)
+		}
+		return secretJSON, nil
+	}
+
+	if name == "" {
+		name = secretJSON.DefaultProfile
+	}
+	names := slices.Sorted(maps.Keys(secretJSON.Profiles))
+	if name == "" {
+		return secretJSON, fmt.Errorf("the spec has profiles, please select one with --profile or HARPOCRATES_PROFILE, either: %s", strings.Join(names, ", "))
+	}
+	profile, ok := secretJSON.Profiles[name]
+	if !ok {
+		return secretJSON, fmt.Errorf("unknown profile '%s', please use either: %s", name, strings.Join(names, ", "))
+	}
+
+	if profile.Append != nil {
+		secretJSON.Append = profile.Append
+	}
+	if profile.Conflict != "" {
+		secretJSON.Conflict = profile.Conflict
+	}
+	if profile.Duplicates != "" {
+		secretJSON.Duplicates = profile.Duplicates
+	}
+	if profile.Format != "" {
+		secretJSON.Format = profile.Format
+	}
+	if profile.Nested != nil {
+		secretJSON.Nested = profile.Nested
+	}
+	if profile.Order != "" {
+		secretJSON.Order = profile.Order
+	}
+	if profile.Output != "" {
+		secretJSON.Output = profile.Output
+	}
+	if profile.Owner != nil {
+		secretJSON.Owner = profile.Owner
+	}
+	if profile.Prefix != "" {
+		secretJSON.Prefix = profile.Prefix
+	}
+	if profile.UpperCase != nil {
+		secretJSON.UpperCase = profile.UpperCase
+	}
+	if profile.SymlinkSwap != nil {
+		secretJSON.SymlinkSwap = profile.SymlinkSwap
+	}
+	if profile.Template != "" {
+		secretJSON.Template = profile.Template
+	}
+
+	variables := map[string]string{"profile": name}
+	maps.Copy(variables, profile.Variables)
+	// Variables that are not in the profile are left for ExpandEnv
+	e := &expander{lookup: func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}}
+	return e.expandSpec(secretJSON), nil
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func profilesSpec() SecretJSON {
+	prefix := "PROD_"
+	return SecretJSON{
+		Prefix:         "DEV_",
+		DefaultProfile: "dev",
+		Profiles: map[string]Profile{
+			"dev":  {Variables: map[string]string{"team": "platform"}},
+			"prod": {Prefix: prefix, Variables: map[string]string{"team": "platform", "profile": "production"}},
+		},
+		Secrets: []any{
+			"secret/data/${team}/${profile}",
+			map[string]any{"secret/data/shared/${profile}": map[string]any{"keys": []any{"api_key"}}},
+			"secret/data/${unknown}",
+		},
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		profile        string
+		expectedPrefix string
+		expectedPaths  []any
+	}{
+		{"default profile", "", "DEV_", []any{
+			"secret/data/platform/dev",
+			map[string]any{"secret/data/shared/dev": map[string]any{"keys": []any{"api_key"}}},
+			"secret/data/${unknown}",
+		}},
+		{"selected profile with overrides", "prod", "PROD_", []any{
+			"secret/data/platform/production",
+			map[string]any{"secret/data/shared/production": map[string]any{"keys": []any{"api_key"}}},
+			"secret/data/${unknown}",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := profilesSpec().ApplyProfile(tt.profile)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if spec.Prefix != tt.expectedPrefix {
+				t.Errorf("expected prefix %q, got %q", tt.expectedPrefix, spec.Prefix)
+			}
+			if !reflect.DeepEqual(spec.Secrets, tt.expectedPaths) {
+				t.Errorf("expected %v, got %v", tt.expectedPaths, spec.Secrets)
+			}
+		})
+	}
+}
+
+func TestApplyProfileErrors(t *testing.T) {
+	noDefault := profilesSpec()
+	noDefault.DefaultProfile = ""
+
+	tests := []struct {
+		name     string
+		spec     SecretJSON
+		profile  string
+		contains string
+	}{
+		{"unknown profile", profilesSpec(), "test", "unknown profile 'test', please use either: dev, prod"},
+		{"no profile selected", noDefault, "", "please select one"},
+		{"spec without profiles", SecretJSON{}, "dev", "the spec has no profiles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.spec.ApplyProfile(tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("expected an error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"sync"
 	"syscall"
 
 	"github.com/creack/pty"
@@ -11,7 +12,31 @@ import (
 )
 
 // RunCmdPTY runs the given command in a pseudo-terminal
-func RunCmdPTY(cmd *exec.Cmd, secretEnvs []string, redact bool) error {
+func RunCmdPTY(cmd *exec.Cmd, secretValues []string, redact bool) error {
+	session := NewPTYSession(redact)
+	defer session.Close()
+
+	return session.Run(cmd, secretValues)
+}
+
+// PTYSession runs commands one after another in pseudo-terminals attached to the same terminal.
+// The terminal stays in raw mode and stdin keeps being read between commands, so restarting a command doesn't disturb the terminal.
+type PTYSession struct {
+	redact bool
+
+	mu        sync.Mutex
+	current   *os.File
+	restore   func()
+	stdinOnce sync.Once
+}
+
+// NewPTYSession returns a PTYSession, Close must be called when done to restore the terminal
+func NewPTYSession(redact bool) *PTYSession {
+	return &PTYSession{redact: redact}
+}
+
+// Run runs the given command in a new pseudo-terminal and waits for it to finish
+func (s *PTYSession) Run(cmd *exec.Cmd, secretValues []string) error {
 	// Start the command with a pseudo-terminal.
 	ptyFile, err := pty.Start(cmd)
 	if err != nil {
@@ -28,29 +53,28 @@ func RunCmdPTY(cmd *exec.Cmd, secretEnvs []string, redact bool) error {
 		defer cleanupResize()
 
 		// Put the true os.Stdin into raw mode to capture Ctrl+C, etc.
-		oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
-		if err != nil {
+		if err := s.makeRaw(); err != nil {
 			// Will try cleanup up the process so that we don't have any zombie processes.
 			cmd.Process.Kill() //nolint:errcheck // We are already in an error state, don't care if this also fails
 			cmd.Wait()         //nolint:errcheck // We are already in an error state, don't care if this also fails
 			return err
 		}
-		defer func() { _ = term.Restore(int(os.Stdin.Fd()), oldState) }() // Important: Restore on exit
 	}
 
-	// Copy os.Stdin directly to the pseudo-terminal
-	go func() {
-		_, _ = io.Copy(ptyFile, os.Stdin)
-	}()
+	// Copy os.Stdin to the pseudo-terminal
+	s.setCurrent(ptyFile)
+	defer s.setCurrent(nil)
+	s.stdinOnce.Do(func() { go s.copyStdin() })
 
-	redactor := &Redactor{
-		Writer: os.Stdout,
-		Envs:   secretEnvs,
-		Redact: redact,
+	var output io.Writer = os.Stdout
+	if s.redact {
+		redactor := NewRedactor(os.Stdout, secretValues)
+		defer func() { _ = redactor.Flush() }() // Writes what is left when the command has finished
+		output = redactor
 	}
 
 	// Copy the ptyFile output through our redactor back to os.Stdout
-	_, err = io.Copy(redactor, ptyFile)
+	_, err = io.Copy(output, ptyFile)
 	if err != nil {
 		pathErr, ok := err.(*os.PathError)
 		if !ok || pathErr.Err != syscall.EIO {
@@ -63,3 +87,54 @@ func RunCmdPTY(cmd *exec.Cmd, secretEnvs []string, redact bool) error {
 	// Wait for the command to terminate to return its exit error mapping.
 	return cmd.Wait()
 }
+
+// Close restores the terminal to the state it had before the first command
+func (s *PTYSession) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.restore != nil {
+		s.restore() // Important: Restore on exit
+		s.restore = nil
+	}
+}
+
+func (s *PTYSession) makeRaw() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.restore != nil {
+		return nil
+	}
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	s.restore = func() { _ = term.Restore(int(os.Stdin.Fd()), oldState) }
+	return nil
+}
+
+func (s *PTYSession) setCurrent(ptyFile *os.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = ptyFile
+}
+
+// copyStdin copies stdin to the pseudo-terminal of the running command, input while no command runs is dropped
+func (s *PTYSession) copyStdin() {
+	buf := make([]byte, 1024)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			s.mu.Lock()
+			if s.current != nil {
+				_, _ = s.current.Write(buf[:n]) // The command may have exited, its input is lost with it
+			}
+			s.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -2,8 +2,10 @@ package util
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/BESTSELLER/harpocrates/config"
 	"go.yaml.in/yaml/v4"
@@ -11,26 +13,45 @@ import (
 
 // SecretJSON holds the information about which secrets to fetch and how to save them again
 type SecretJSON struct {
-	Append        *bool  `json:"append,omitempty"      yaml:"append,omitempty"`
-	Format        string `json:"format,omitempty"      yaml:"format,omitempty"`
-	Output        string `json:"output,omitempty"      yaml:"output,omitempty"`
-	Owner         *int   `json:"owner,omitempty"       yaml:"owner,omitempty"`
-	Prefix        string `json:"prefix,omitempty"      yaml:"prefix,omitempty"`
-	UpperCase     *bool  `json:"uppercase,omitempty"   yaml:"uppercase,omitempty"`
-	Secrets       []any  `json:"secrets,omitempty"     yaml:"secrets,omitempty"`
-	GcpWorkloadID bool   `json:"gcpWorkloadID,omitempty"     yaml:"gcpWorkloadID,omitempty"`
+	APIVersion     string             `json:"apiVersion,omitempty"  yaml:"apiVersion,omitempty"`
+	Append         *bool              `json:"append,omitempty"      yaml:"append,omitempty"`
+	Conflict       string             `json:"conflict,omitempty"    yaml:"conflict,omitempty"`
+	DefaultProfile string             `json:"defaultProfile,omitempty" yaml:"defaultProfile,omitempty"`
+	Duplicates     string             `json:"duplicates,omitempty"  yaml:"duplicates,omitempty"`
+	Format         string             `json:"format,omitempty"      yaml:"format,omitempty"`
+	Include        []string           `json:"include,omitempty"     yaml:"include,omitempty"`
+	Nested         *bool              `json:"nested,omitempty"      yaml:"nested,omitempty"`
+	Order          string             `json:"order,omitempty"       yaml:"order,omitempty"`
+	Output         string             `json:"output,omitempty"      yaml:"output,omitempty"`
+	Owner          *int               `json:"owner,omitempty"       yaml:"owner,omitempty"`
+	Prefix         string             `json:"prefix,omitempty"      yaml:"prefix,omitempty"`
+	Profiles       map[string]Profile `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	UpperCase      *bool              `json:"uppercase,omitempty"   yaml:"uppercase,omitempty"`
+	Secrets        []any              `json:"secrets,omitempty"     yaml:"secrets,omitempty"`
+	SymlinkSwap    *bool              `json:"symlinkSwap,omitempty" yaml:"symlinkSwap,omitempty"`
+	Template       string             `json:"template,omitempty"    yaml:"template,omitempty"`
+	GcpWorkloadID  bool               `json:"gcpWorkloadID,omitempty"     yaml:"gcpWorkloadID,omitempty"`
+	// IncludedFiles are the spec files merged into this spec by include
+	IncludedFiles []string `json:"-" yaml:"-"`
 }
 
 // Secret holds the configuration for a secret
 type Secret struct {
-	Append    *bool  `json:"append,omitempty"      yaml:"append,omitempty"`
-	Prefix    string `json:"prefix,omitempty"      yaml:"prefix,omitempty"`
-	Format    string `json:"format,omitempty"      yaml:"format,omitempty"      mapstructure:"format,omitempty"`
-	FileName  string `json:"filename,omitempty"    yaml:"filename,omitempty"    mapstructure:"filename,omitempty"`
-	UpperCase *bool  `json:"uppercase,omitempty"   yaml:"uppercase,omitempty"`
-	Optional  *bool  `json:"optional,omitempty"    yaml:"optional,omitempty"    mapstructure:"optional,omitempty"`
-	Keys      []any  `json:"keys,omitempty"        yaml:"keys,omitempty"`
-	Owner     *int   `json:"owner,omitempty"       yaml:"owner,omitempty"`
+	Append      *bool    `json:"append,omitempty"      yaml:"append,omitempty"`
+	Prefix      string   `json:"prefix,omitempty"      yaml:"prefix,omitempty"`
+	Format      string   `json:"format,omitempty"      yaml:"format,omitempty"      mapstructure:"format,omitempty"`
+	FileName    string   `json:"filename,omitempty"    yaml:"filename,omitempty"    mapstructure:"filename,omitempty"`
+	Nested      *bool    `json:"nested,omitempty"      yaml:"nested,omitempty"      mapstructure:"nested,omitempty"`
+	UpperCase   *bool    `json:"uppercase,omitempty"   yaml:"uppercase,omitempty"`
+	Optional    *bool    `json:"optional,omitempty"    yaml:"optional,omitempty"    mapstructure:"optional,omitempty"`
+	Keys        []any    `json:"keys,omitempty"        yaml:"keys,omitempty"`
+	RequireKeys []string `json:"requireKeys,omitempty" yaml:"requireKeys,omitempty" mapstructure:"requireKeys,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"     yaml:"exclude,omitempty"     mapstructure:"exclude,omitempty"`
+	Rename      []string `json:"rename,omitempty"      yaml:"rename,omitempty"      mapstructure:"rename,omitempty"`
+	Owner       *int     `json:"owner,omitempty"       yaml:"owner,omitempty"`
+	Group       *int     `json:"group,omitempty"       yaml:"group,omitempty"       mapstructure:"group,omitempty"`
+	Mode        string   `json:"mode,omitempty"        yaml:"mode,omitempty"        mapstructure:"mode,omitempty"`
+	Template    string   `json:"template,omitempty"    yaml:"template,omitempty"    mapstructure:"template,omitempty"`
 }
 
 // SecretKeys holds the configuration for secret keys
@@ -41,25 +62,60 @@ type SecretKeys struct {
 	Optional   *bool  `json:"optional,omitempty"       yaml:"optional,omitempty"    mapstructure:"optional,omitempty"`
 	SaveAsFile *bool  `json:"saveAsFile,omitempty"     yaml:"saveAsFile,omitempty"`
 	Alias      string `json:"alias,omitempty"          yaml:"alias,omitempty"       mapstructure:"alias,omitempty"`
+	Transform  []any  `json:"transform,omitempty"      yaml:"transform,omitempty"   mapstructure:"transform,omitempty"`
+	FileName   string `json:"filename,omitempty"       yaml:"filename,omitempty"    mapstructure:"filename,omitempty"`
+	Mode       string `json:"mode,omitempty"           yaml:"mode,omitempty"        mapstructure:"mode,omitempty"`
+	Group      *int   `json:"group,omitempty"          yaml:"group,omitempty"       mapstructure:"group,omitempty"`
+	Default    any    `json:"default,omitempty"        yaml:"default,omitempty"     mapstructure:"default,omitempty"`
+	Pattern    string `json:"pattern,omitempty"        yaml:"pattern,omitempty"     mapstructure:"pattern,omitempty"`
 }
 
 // ReadInput will read the input given to Harpocrates and try to parse it to SecretJSON
 // Will also set some default values
 func ReadInput(input string) SecretJSON {
-	secretJSON := SecretJSON{}
-	err := json.Unmarshal([]byte(input), &secretJSON)
+	return ReadInputFrom(input, "")
+}
+
+// ReadInputFrom is ReadInput for a spec read from specFile, which the includes of the spec are relative to
+func ReadInputFrom(input string, specFile string) SecretJSON {
+	secretJSON, err := LoadInput(input, specFile)
 	if err != nil {
-		err = yaml.Unmarshal([]byte(input), &secretJSON)
-		if err != nil {
-			fmt.Printf("Your secret file contains an error, please refer to the documentation\n%v\n", err)
-			os.Exit(1)
-		}
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return secretJSON
+}
+
+// LoadInput is ReadInputFrom returning an error instead of exiting, for callers that must keep running such as dev --watch
+func LoadInput(input string, specFile string) (SecretJSON, error) {
+	secretJSON, err := parseSpec(input)
+	if err != nil {
+		return secretJSON, fmt.Errorf("your secret file contains an error, please refer to the documentation: %w", err)
+	}
+
+	secretJSON, err = secretJSON.ResolveIncludes(specFile)
+	if err != nil {
+		return secretJSON, fmt.Errorf("unable to include the specs in your secret file: %w", err)
+	}
+
+	secretJSON, err = secretJSON.ApplyProfile(config.Config.Profile)
+	if err != nil {
+		return secretJSON, fmt.Errorf("unable to select a profile: %w", err)
+	}
+
+	secretJSON, err = secretJSON.ExpandEnv(config.Config.StrictVariables)
+	if err != nil {
+		return secretJSON, fmt.Errorf("unable to fill in the variables of your secret file: %w", err)
 	}
 
 	if secretJSON.Format != "" {
 		config.Config.Format = secretJSON.Format
 	}
 
+	if secretJSON.Template != "" {
+		config.Config.Template = secretJSON.Template
+	}
+
 	if secretJSON.Output == "" {
 		secretJSON.Output = "/secrets"
 	}
@@ -74,8 +130,7 @@ func ReadInput(input string) SecretJSON {
 	config.Config.Owner = *secretJSON.Owner
 
 	if len(secretJSON.Secrets) == 0 {
-		fmt.Println("No secrets provided")
-		os.Exit(1)
+		return secretJSON, errors.New("no secrets provided")
 	}
 
 	config.Config.Prefix = secretJSON.Prefix
@@ -84,13 +139,74 @@ func ReadInput(input string) SecretJSON {
 		config.Config.UpperCase = *secretJSON.UpperCase
 	}
 
+	if secretJSON.Nested != nil {
+		config.Config.Nested = *secretJSON.Nested
+	}
+
 	if secretJSON.Append != nil {
 		config.Config.Append = *secretJSON.Append
 	}
 
+	if secretJSON.Conflict != "" {
+		config.Config.Conflict = secretJSON.Conflict
+	}
+
+	if secretJSON.Duplicates != "" {
+		config.Config.Duplicates = secretJSON.Duplicates
+	}
+
+	if secretJSON.Order != "" {
+		config.Config.Order = secretJSON.Order
+	}
+
+	if secretJSON.SymlinkSwap != nil {
+		config.Config.SymlinkSwap = *secretJSON.SymlinkSwap
+	}
+
 	if secretJSON.GcpWorkloadID {
 		config.Config.GcpWorkloadID = secretJSON.GcpWorkloadID
 	}
 
-	return secretJSON
+	return secretJSON, nil
+}
+
+// parseSpec parses a spec in either json or yaml and checks its apiVersion
+func parseSpec(input string) (SecretJSON, error) {
+	secretJSON := SecretJSON{}
+	if err := parseInto(input, &secretJSON); err != nil {
+		return secretJSON, err
+	}
+	if _, err := checkSpecVersion(secretJSON.APIVersion); err != nil {
+		return secretJSON, err
+	}
+	return secretJSON, nil
+}
+
+// parseInto parses input in either json or yaml into out
+func parseInto[T any](input string, out *T) error {
+	err := json.Unmarshal([]byte(input), out)
+	if err != nil {
+		*out = *new(T)
+		err = yaml.Unmarshal([]byte(input), out)
+	}
+	return err
+}
+
+// Paths returns the Vault paths of all secrets in the spec, in the order they are listed
+func (secretJSON SecretJSON) Paths() []string {
+	var paths []string
+	for _, secretEntry := range secretJSON.Secrets {
+		switch entry := secretEntry.(type) {
+		case string:
+			paths = append(paths, entry)
+		case map[string]any:
+			keys := make([]string, 0, len(entry))
+			for path := range entry {
+				keys = append(keys, path)
+			}
+			slices.Sort(keys)
+			paths = append(paths, keys...)
+		}
+	}
+	return paths
 }
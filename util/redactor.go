@@ -1,35 +1,261 @@
 package util
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"io"
-	"strings"
+	"net/url"
+	"sync"
+	"time"
 )
 
-// Redactor wraps an io.Writer to redact sensitive values from the output.
+// MinRedactLength is the length a secret value needs to be redacted, shorter values such as "true" or a port number would redact too much
+const MinRedactLength = 6
+
+// DefaultIdleTimeout is how long output that could be the start of a secret is held back before it is written anyway
+const DefaultIdleTimeout = 100 * time.Millisecond
+
+const redactedText = "[REDACTED]"
+
+// Redactor wraps an io.Writer to redact secret values from the output.
+//
+// Values are matched across writes, so a secret split over two reads is still redacted.
+// Output that could be the start of a secret is held back until it is clear it isn't, or until nothing is written for IdleTimeout,
+// so interactive output stays responsive. Flush must be called when done to write what is held back.
 type Redactor struct {
-	Writer io.Writer
-	Envs   []string
-	Redact bool
+	Writer      io.Writer
+	IdleTimeout time.Duration
+
+	matcher *matcher
+	mu      sync.Mutex
+	state   int
+	pending []byte
+	// spans are the parts of pending to redact, sorted and not overlapping
+	spans []span
+	timer *time.Timer
+	err   error
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+// NewRedactor returns a Redactor redacting the given values from everything written to w.
+// Besides the values themselves, their base64, URL and JSON encoded forms are redacted too.
+func NewRedactor(w io.Writer, secretValues []string) *Redactor {
+	return &Redactor{
+		Writer:      w,
+		IdleTimeout: DefaultIdleTimeout,
+		matcher:     newMatcher(redactionPatterns(secretValues)),
+	}
+}
+
+// redactionPatterns returns the values and their common encodings, leaving out values shorter than MinRedactLength
+func redactionPatterns(secretValues []string) [][]byte {
+	seen := map[string]bool{}
+	var patterns [][]byte
+	add := func(pattern string) {
+		if !seen[pattern] {
+			seen[pattern] = true
+			patterns = append(patterns, []byte(pattern))
+		}
+	}
+
+	for _, value := range secretValues {
+		if len(value) < MinRedactLength {
+			continue
+		}
+		add(value)
+		// Without padding, so the value is also found when it is not at the end of the encoded text
+		add(base64.RawStdEncoding.EncodeToString([]byte(value)))
+		add(base64.RawURLEncoding.EncodeToString([]byte(value)))
+		add(url.QueryEscape(value))
+		add(url.PathEscape(value))
+		add(jsonEscape(value, true))
+		add(jsonEscape(value, false))
+	}
+	return patterns
+}
+
+func jsonEscape(value string, escapeHTML bool) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(escapeHTML)
+	_ = encoder.Encode(value) // A string can always be encoded
+	encoded := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	return string(encoded[1 : len(encoded)-1])
 }
 
-// Write implements the io.Writer interface, redacting sensitive values from p before writing to the underlying Writer.
+// Write implements the io.Writer interface, redacting secret values from p before writing to the underlying Writer.
 func (r *Redactor) Write(p []byte) (n int, err error) {
-	result := p
-
-	if r.Redact {
-		output := string(p)
-		for _, env := range r.Envs {
-			parts := strings.SplitN(env, "=", 2)
-			if len(parts) == 2 {
-				value := parts[1]
-				if value != "" {
-					output = strings.ReplaceAll(output, value, "[REDACTED]")
-				}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	for _, b := range p {
+		r.pending = append(r.pending, b)
+		r.state = r.matcher.step(r.state, b)
+		if length := r.matcher.nodes[r.state].match; length > 0 {
+			r.addSpan(len(r.pending)-length, len(r.pending))
+		}
+	}
+
+	// Everything before the longest possible start of a secret is safe to write
+	if err := r.flush(len(r.pending)-r.matcher.nodes[r.state].depth, false); err != nil {
+		r.err = err
+		return 0, err
+	}
+
+	if len(r.pending) > 0 {
+		if r.timer == nil {
+			r.timer = time.AfterFunc(r.IdleTimeout, r.idleFlush)
+		} else {
+			r.timer.Reset(r.IdleTimeout)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the output that is held back
+func (r *Redactor) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return r.flushAll()
+}
+
+func (r *Redactor) idleFlush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = r.flushAll()
+	}
+}
+
+// addSpan marks pending[start:end] to be redacted, merging it with the spans it overlaps
+func (r *Redactor) addSpan(start int, end int) {
+	for len(r.spans) > 0 {
+		last := r.spans[len(r.spans)-1]
+		if last.end < start {
+			break
+		}
+		start = min(start, last.start)
+		end = max(end, last.end)
+		r.spans = r.spans[:len(r.spans)-1]
+	}
+	r.spans = append(r.spans, span{start: start, end: end})
+}
+
+// flush writes pending up to limit.
+// Unless final is set, a redacted span that doesn't end before limit is held back as a longer match could extend it.
+func (r *Redactor) flush(limit int, final bool) error {
+	for _, s := range r.spans {
+		if !final && s.start < limit && s.end >= limit {
+			limit = s.start
+			break
+		}
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	var out bytes.Buffer
+	last := 0
+	remaining := r.spans[:0]
+	for _, s := range r.spans {
+		if s.start >= limit {
+			remaining = append(remaining, span{start: s.start - limit, end: s.end - limit})
+			continue
+		}
+		out.Write(r.pending[last:s.start])
+		out.WriteString(redactedText)
+		last = s.end
+	}
+	out.Write(r.pending[last:limit])
+
+	r.spans = remaining
+	r.pending = append(r.pending[:0], r.pending[limit:]...)
+	_, err := r.Writer.Write(out.Bytes())
+	return err
+}
+
+// flushAll writes everything in pending and starts matching from scratch
+func (r *Redactor) flushAll() error {
+	r.state = 0
+	return r.flush(len(r.pending), true)
+}
+
+// matcher is an Aho-Corasick automaton finding all patterns in a single pass over the output
+type matcher struct {
+	nodes []matcherNode
+}
+
+type matcherNode struct {
+	next map[byte]int
+	fail int
+	// depth is the length of the text leading to this node
+	depth int
+	// match is the length of the longest pattern ending at this node, 0 if none does
+	match int
+}
+
+func newMatcher(patterns [][]byte) *matcher {
+	m := &matcher{nodes: []matcherNode{{next: map[byte]int{}}}}
+
+	for _, pattern := range patterns {
+		current := 0
+		for _, b := range pattern {
+			next, ok := m.nodes[current].next[b]
+			if !ok {
+				next = len(m.nodes)
+				m.nodes = append(m.nodes, matcherNode{next: map[byte]int{}, depth: m.nodes[current].depth + 1})
+				m.nodes[current].next[b] = next
 			}
+			current = next
 		}
-		result = []byte(output)
+		m.nodes[current].match = len(pattern)
 	}
 
-	_, err = r.Writer.Write(result)
-	return len(p), err
+	// Breadth first, so the fail link of a node is done before its children need it
+	queue := []int{}
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for b, child := range m.nodes[current].next {
+			m.nodes[child].fail = m.step(m.nodes[current].fail, b)
+			if m.nodes[child].match == 0 {
+				m.nodes[child].match = m.nodes[m.nodes[child].fail].match
+			}
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// step returns the state after reading b in state
+func (m *matcher) step(state int, b byte) int {
+	for {
+		if next, ok := m.nodes[state].next[b]; ok {
+			return next
+		}
+		if state == 0 {
+			return 0
+		}
+		state = m.nodes[state].fail
+	}
 }
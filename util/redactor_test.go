@@ -0,0 +1,96 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// safeBuffer is a bytes.Buffer that can be written by the idle flush while the test reads it
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func redactChunks(t *testing.T, values []string, chunks ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	redactor := NewRedactor(&out, values)
+	for _, chunk := range chunks {
+		if _, err := redactor.Write([]byte(chunk)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := redactor.Flush(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return out.String()
+}
+
+func TestRedactor(t *testing.T) {
+	secret := "s3cr3t/p@ss"
+
+	tests := []struct {
+		name     string
+		values   []string
+		chunks   []string
+		expected string
+	}{
+		{"plain", []string{secret}, []string{"password: " + secret + "\n"}, "password: [REDACTED]\n"},
+		{"split across writes", []string{secret}, []string{"password: s3c", "r3t/p", "@ss\n"}, "password: [REDACTED]\n"},
+		{"split byte by byte", []string{"abcdefgh"}, []string{"x", "a", "b", "c", "d", "e", "f", "g", "h", "y"}, "x[REDACTED]y"},
+		{"base64", []string{secret}, []string{"b64: " + base64.StdEncoding.EncodeToString([]byte(secret))}, "b64: [REDACTED]="},
+		{"url encoded", []string{secret}, []string{"https://host/?p=" + url.QueryEscape(secret)}, "https://host/?p=[REDACTED]"},
+		{"json escaped", []string{"line1\nline2\"x"}, []string{`{"value":"line1\nline2\"x"}`}, `{"value":"[REDACTED]"}`},
+		{"short values are kept", []string{"true", "5432"}, []string{"enabled=true port=5432"}, "enabled=true port=5432"},
+		{"longest match wins", []string{"abcdef", "abcdefghij"}, []string{"abcdefghij abcdefgh"}, "[REDACTED] [REDACTED]gh"},
+		{"overlapping values", []string{"abcdefg", "efghijk"}, []string{"abcdefghijk"}, "[REDACTED]"},
+		{"partial match is written", []string{secret}, []string{"s3cr3t/p@s", "x"}, "s3cr3t/p@sx"},
+		{"no values", nil, []string{"nothing to hide"}, "nothing to hide"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := redactChunks(t, tt.values, tt.chunks...); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRedactorFlushesOnIdle(t *testing.T) {
+	var out safeBuffer
+	redactor := NewRedactor(&out, []string{"s3cr3t/p@ss"})
+	redactor.IdleTimeout = 10 * time.Millisecond
+
+	// A prompt that looks like the start of the secret must not be held back forever
+	if _, err := redactor.Write([]byte("enter s3cr")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out.String() != "enter " {
+		t.Errorf("expected the possible secret to be held back, got %q", out.String())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for out.String() != "enter s3cr" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if out.String() != "enter s3cr" {
+		t.Errorf("expected the output to be written when idle, got %q", out.String())
+	}
+}
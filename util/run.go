@@ -0,0 +1,51 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// RunCmd runs the given command without a pseudo-terminal.
+// stdout and stderr are kept apart and go through separate redactors, so the output can be piped as usual.
+// Signals sent to harpocrates are forwarded to the command, so it can shut down gracefully.
+func RunCmd(cmd *exec.Cmd, secretValues []string, redact bool) error {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if redact {
+		stdout := NewRedactor(os.Stdout, secretValues)
+		stderr := NewRedactor(os.Stderr, secretValues)
+		// Wait returns when all output has been copied, what is held back is written after that
+		defer func() { _ = stdout.Flush() }()
+		defer func() { _ = stderr.Flush() }()
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+	}
+
+	restoreTerminal := setProcessGroup(cmd)
+	defer restoreTerminal()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	stopForwarding := forwardSignals(cmd.Process)
+	defer stopForwarding()
+
+	return cmd.Wait()
+}
+
+// ExitCode returns the exit code of a command that failed.
+// A command killed by a signal gets 128 + the signal number, like in a shell.
+func ExitCode(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), true
+	}
+	return exitErr.ExitCode(), true
+}
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestRunCmd_Success(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo 'hello, world'; echo 'to stderr' >&2")
+
+	if err := RunCmd(cmd, []string{"SUPER_SECRET_ENV=secret"}, true); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestRunCmd_ExitCode(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 42")
+
+	err := RunCmd(cmd, []string{}, false)
+	exitCode, ok := ExitCode(err)
+	if !ok {
+		t.Fatalf("expected an exit code, got %T: %v", err, err)
+	}
+	if exitCode != 42 {
+		t.Errorf("expected exit code 42, got %d", exitCode)
+	}
+}
@@ -0,0 +1,90 @@
+//go:build !windows
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// forwardedSignals are sent on to the process group of the command
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// setProcessGroup starts the command in its own process group, so signals can be sent to it and the processes it starts.
+// When stdin is a terminal the group becomes the foreground group, so Ctrl+C from the terminal reaches the command only once.
+// The returned function gives the terminal back to harpocrates.
+func setProcessGroup(cmd *exec.Cmd) func() {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return func() {}
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = stdin
+
+	return func() {
+		// harpocrates is in the background now, taking the terminal back would stop it with SIGTTOU
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		_ = unix.IoctlSetPointerInt(stdin, unix.TIOCSPGRP, syscall.Getpgrp()) // Best effort, the shell takes the terminal back when we exit anyway
+	}
+}
+
+// forwardSignals sends the signals harpocrates receives on to the process group of process, until the returned function is called
+func forwardSignals(process *os.Process) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, forwardedSignals...)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = syscall.Kill(-process.Pid, sig.(syscall.Signal)) // The process may have exited already
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// signalNames holds the signals that can be used to stop a command
+var signalNames = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// ParseSignal returns the signal with the given name, e.g. SIGTERM or TERM
+func ParseSignal(name string) (os.Signal, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signalNames[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown signal '%s', please use either: SIGHUP, SIGINT, SIGQUIT, SIGTERM, SIGUSR1 or SIGUSR2", name)
+	}
+	return sig, nil
+}
+
+// StopProcessGroup sends sig to the process group of a command started by RunCmd or RunCmdPTY, so the processes it started stop too
+func StopProcessGroup(process *os.Process, sig os.Signal) error {
+	return syscall.Kill(-process.Pid, sig.(syscall.Signal))
+}
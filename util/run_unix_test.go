@@ -0,0 +1,44 @@
+//go:build !windows
+
+package util
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunCmd_ForwardsSignals(t *testing.T) {
+	// The child handles SIGTERM itself and exits with a code of its own choosing
+	cmd := exec.Command("sh", "-c", "trap 'exit 7' TERM; while true; do sleep 0.1; done")
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+
+	err := RunCmd(cmd, []string{}, false)
+	if exitCode, _ := ExitCode(err); exitCode != 7 {
+		t.Errorf("expected exit code 7 from the trap, got %d: %v", exitCode, err)
+	}
+}
+
+func TestExitCode_Signaled(t *testing.T) {
+	err := exec.Command("sh", "-c", "kill -KILL $$").Run()
+
+	if exitCode, _ := ExitCode(err); exitCode != 128+int(syscall.SIGKILL) {
+		t.Errorf("expected exit code %d, got %d", 128+int(syscall.SIGKILL), exitCode)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"SIGTERM", "TERM", "term"} {
+		if sig, err := ParseSignal(name); err != nil || sig != syscall.SIGTERM {
+			t.Errorf("expected SIGTERM for %q, got %v, %v", name, sig, err)
+		}
+	}
+	if _, err := ParseSignal("SIGFOO"); err == nil {
+		t.Errorf("expected an error for an unknown signal")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package util
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+func setProcessGroup(cmd *exec.Cmd) func() {
+	// Windows has no process groups to signal, the console sends Ctrl+C to every attached process.
+	return func() {}
+}
+
+// forwardSignals keeps harpocrates running on Ctrl+C, the console already delivers it to the command
+func forwardSignals(process *os.Process) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	return func() {
+		signal.Stop(signals)
+	}
+}
+
+// ParseSignal accepts any signal name, Windows can only kill a command
+func ParseSignal(name string) (os.Signal, error) {
+	return os.Kill, nil
+}
+
+// StopProcessGroup kills the command, Windows has no signals to ask it to stop
+func StopProcessGroup(process *os.Process, sig os.Signal) error {
+	return process.Kill()
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"go.yaml.in/yaml/v4"
+)
+
+// Scaffold describes a new spec, as created by harpocrates init
+type Scaffold struct {
+	Format  string
+	Output  string
+	Secrets []ScaffoldSecret
+}
+
+// ScaffoldSecret is a secret of a new spec, every key of the secret is fetched when no keys are given
+type ScaffoldSecret struct {
+	Path string
+	Keys []string
+}
+
+// Render writes the spec as yaml at LatestSpecVersion, with comments explaining each option
+func (scaffold Scaffold) Render() (string, error) {
+	secrets := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, secret := range scaffold.Secrets {
+		if len(secret.Keys) == 0 {
+			path := scalarNode(secret.Path)
+			path.LineComment = "fetches every key of the secret"
+			secrets.Content = append(secrets.Content, path)
+			continue
+		}
+
+		keys := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, key := range secret.Keys {
+			keys.Content = append(keys.Content, scalarNode(key))
+		}
+		keysKey := scalarNode("keys")
+		keysKey.HeadComment = "only these keys are fetched, each can be given e.g. a prefix, alias or saveAsFile"
+		options := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{keysKey, keys}}
+		secrets.Content = append(secrets.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{scalarNode(secret.Path), options}})
+	}
+
+	apiVersion := scalarNode("apiVersion")
+	apiVersion.HeadComment = "Created with harpocrates init, fetch the secrets with: harpocrates fetch -f <this file>\n" +
+		"See https://github.com/BESTSELLER/harpocrates for all the options of a spec."
+	format := scalarNode("format")
+	format.HeadComment = "The format of the files, e.g. env, json or yaml"
+	output := scalarNode("output")
+	output.HeadComment = "The folder the files are written to"
+	secretsKey := scalarNode("secrets")
+	secretsKey.HeadComment = "The secrets to fetch from Vault"
+
+	// Without a format the spec falls back to the default format, env
+	root := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{apiVersion, scalarNode(LatestSpecVersion)}}
+	if scaffold.Format != "" {
+		root.Content = append(root.Content, format, scalarNode(scaffold.Format))
+	}
+	root.Content = append(root.Content, output, scalarNode(scaffold.Output), secretsKey, secrets)
+	return encodeYAML(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}})
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
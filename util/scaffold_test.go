@@ -0,0 +1,70 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScaffoldRender(t *testing.T) {
+	scaffold := Scaffold{
+		Format: "env",
+		Output: "/secrets",
+		Secrets: []ScaffoldSecret{
+			{Path: "kv/data/app/dev"},
+			{Path: "kv/data/app/shared", Keys: []string{"password", "api.key"}},
+		},
+	}
+
+	actual, err := scaffold.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Created with harpocrates init, fetch the secrets with: harpocrates fetch -f <this file>
+# See https://github.com/BESTSELLER/harpocrates for all the options of a spec.
+apiVersion: v2
+# The format of the files, e.g. env, json or yaml
+format: env
+# The folder the files are written to
+output: /secrets
+# The secrets to fetch from Vault
+secrets:
+  - kv/data/app/dev # fetches every key of the secret
+  - kv/data/app/shared:
+      # only these keys are fetched, each can be given e.g. a prefix, alias or saveAsFile
+      keys:
+        - password
+        - api.key
+`
+	if actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+
+	spec, err := parseSpec(actual)
+	if err != nil {
+		t.Fatalf("expected the scaffold to be a valid spec, got %v", err)
+	}
+	if len(spec.Secrets) != 2 || spec.Format != "env" || spec.Output != "/secrets" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+}
+
+func TestScaffoldRenderWithoutFormat(t *testing.T) {
+	scaffold := Scaffold{Output: "/secrets", Secrets: []ScaffoldSecret{{Path: "kv/data/app/dev"}}}
+
+	actual, err := scaffold.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(actual, "format:") {
+		t.Errorf("expected no format in the scaffold, got\n%s", actual)
+	}
+
+	spec, err := parseSpec(actual)
+	if err != nil {
+		t.Fatalf("expected the scaffold to be a valid spec, got %v", err)
+	}
+	if spec.Format != "" || spec.Output != "/secrets" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+}
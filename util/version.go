@@ -0,0 +1,41 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// SpecVersionV1 is the version of a spec without an apiVersion
+	SpecVersionV1 = "v1"
+	// SpecVersionV2 requires the apiVersion and drops the fileName alias of filename
+	SpecVersionV2 = "v2"
+	// LatestSpecVersion is the version new specs are written in and old specs are migrated to
+	LatestSpecVersion = SpecVersionV2
+)
+
+// SpecVersions lists the apiVersions a spec can have, oldest first
+var SpecVersions = []string{SpecVersionV1, SpecVersionV2}
+
+// SpecVersion returns the apiVersion of a spec in either json or yaml, a spec without one is SpecVersionV1
+func SpecVersion(input string) (string, error) {
+	var spec struct {
+		APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	}
+	if err := parseInto(input, &spec); err != nil {
+		return "", err
+	}
+	return checkSpecVersion(spec.APIVersion)
+}
+
+// checkSpecVersion checks an apiVersion, an empty one gives SpecVersionV1
+func checkSpecVersion(version string) (string, error) {
+	if version == "" {
+		return SpecVersionV1, nil
+	}
+	if !slices.Contains(SpecVersions, version) {
+		return "", fmt.Errorf("unknown apiVersion '%s', please use either: %s", version, strings.Join(SpecVersions, ", "))
+	}
+	return version, nil
+}
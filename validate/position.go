@@ -0,0 +1,65 @@
+package validate
+
+import (
+	"errors"
+	"strconv"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// parseNode parses a spec in either json or yaml into its syntax tree, so problems can be given a position.
+// A syntax error is returned as a Problem.
+func parseNode(content string) (*yaml.Node, *Problem) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		problem := Problem{Rule: "syntax", Message: err.Error()}
+		var loadErr *yaml.LoadError
+		if errors.As(err, &loadErr) {
+			problem.Line, problem.Column, problem.Message = loadErr.Mark.Line, loadErr.Mark.Column, loadErr.Message
+		}
+		return nil, &problem
+	}
+	if len(document.Content) == 0 {
+		return &document, nil
+	}
+	return document.Content[0], nil
+}
+
+// nodeAt returns the node at path in the syntax tree, or the deepest node on the way to it that exists.
+// For an option of a mapping it returns its key, so the position points at the name of the option.
+func nodeAt(root *yaml.Node, path []string) *yaml.Node {
+	value, position := root, root
+	for _, segment := range path {
+		key, next := childNode(value, segment)
+		if next == nil {
+			break
+		}
+		value, position = next, key
+	}
+	return position
+}
+
+// childNode returns the key and value of an option of a mapping, or an item of a sequence as both
+func childNode(node *yaml.Node, segment string) (*yaml.Node, *yaml.Node) {
+	if node == nil {
+		return nil, nil
+	}
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				return node.Content[i], node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(segment)
+		if err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index], node.Content[index]
+		}
+	}
+	return nil, nil
+}
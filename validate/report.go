@@ -0,0 +1,181 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ReportFormats lists the formats a Report can be written in
+var ReportFormats = []string{"text", "json", "sarif"}
+
+// Problem is an error found in a secrets file
+type Problem struct {
+	// File is the spec file the problem is in, empty for an inline spec
+	File string `json:"file,omitempty"`
+	// Line and Column are where the problem is in the file, starting at 1, or 0 when it is not known
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Field is the path to the option with the problem, e.g. secrets.0.secret/data/app.format
+	Field string `json:"field,omitempty"`
+	// Rule names the kind of problem, e.g. syntax or additional_property_not_allowed
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// String returns the problem as file:line:col: message
+func (problem Problem) String() string {
+	file := problem.File
+	if file == "" {
+		file = "<inline>"
+	}
+	if problem.Line == 0 {
+		return fmt.Sprintf("%s: %s", file, problem.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", file, problem.Line, problem.Column, problem.Message)
+}
+
+// Report holds the problems found in a secrets file and the specs it includes
+type Report struct {
+	Problems []Problem `json:"problems"`
+}
+
+// Valid reports whether no problems were found
+func (report Report) Valid() bool {
+	return len(report.Problems) == 0
+}
+
+// Format writes the report as text, json or sarif
+func (report Report) Format(format string) (string, error) {
+	switch format {
+	case "", "text":
+		return report.Text(), nil
+	case "json":
+		return report.JSON()
+	case "sarif":
+		return report.SARIF()
+	default:
+		return "", fmt.Errorf("unknown report format '%s', please use either: %s", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// Text returns a line per problem
+func (report Report) Text() string {
+	var text strings.Builder
+	for _, problem := range report.Problems {
+		text.WriteString(problem.String())
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+// JSON returns the report as indented json
+func (report Report) JSON() (string, error) {
+	if report.Problems == nil {
+		report.Problems = []Problem{}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// SARIF returns the report as a SARIF 2.1.0 log, which CI systems such as GitHub code scanning show as annotations
+func (report Report) SARIF() (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "harpocrates",
+			InformationURI: "https://github.com/BESTSELLER/harpocrates",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	var rules []string
+	for _, problem := range report.Problems {
+		if !slices.Contains(rules, problem.Rule) {
+			rules = append(rules, problem.Rule)
+		}
+
+		result := sarifResult{RuleID: problem.Rule, Level: "error", Message: sarifMessage{Text: problem.Message}}
+		if problem.File != "" {
+			location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(problem.File)}}
+			if problem.Line > 0 {
+				location.Region = &sarifRegion{StartLine: problem.Line, StartColumn: problem.Column}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: location}}
+		}
+		run.Results = append(run.Results, result)
+	}
+	slices.Sort(rules)
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: rule})
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,71 @@
+package validate
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReportFormat(t *testing.T) {
+	report := Report{Problems: []Problem{
+		{File: "secrets.yaml", Line: 4, Column: 7, Field: "format", Rule: "enum", Message: "format: must be one of env, json"},
+		{Rule: "syntax", Message: "did not find expected key"},
+	}}
+
+	text, err := report.Format("text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "secrets.yaml:4:7: format: must be one of env, json\n<inline>: did not find expected key\n"; text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+
+	out, err := report.Format("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil || len(decoded.Problems) != 2 || decoded.Problems[0] != report.Problems[0] {
+		t.Errorf("expected the json report to hold the problems, got %s (%v)", out, err)
+	}
+
+	out, err = report.Format("sarif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sarif sarifLog
+	if err := json.Unmarshal([]byte(out), &sarif); err != nil {
+		t.Fatal(err)
+	}
+	run := sarif.Runs[0]
+	if sarif.Version != "2.1.0" || len(run.Results) != 2 || len(run.Tool.Driver.Rules) != 2 {
+		t.Fatalf("expected a SARIF log with 2 results and rules, got %s", out)
+	}
+	region := run.Results[0].Locations[0].PhysicalLocation.Region
+	if region == nil || region.StartLine != 4 || region.StartColumn != 7 {
+		t.Errorf("expected the result to be at 4:7, got %+v", region)
+	}
+	if len(run.Results[1].Locations) != 0 {
+		t.Errorf("expected no location for a problem without a file, got %+v", run.Results[1].Locations)
+	}
+
+	if _, err := report.Format("xml"); err == nil {
+		t.Error("expected an error for an unknown report format")
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"filename", "fileName", "format", "saveAsFile"}
+	tests := map[string]string{
+		"saveasfile": "saveAsFile",
+		"filname":    "filename",
+		"fileNme":    "fileName",
+		"fromat":     "format",
+		"template":   "",
+	}
+
+	for name, expected := range tests {
+		if got := suggest(name, candidates); got != expected {
+			t.Errorf("expected %q for %q, got %q", expected, name, got)
+		}
+	}
+}
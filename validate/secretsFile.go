@@ -1,45 +1,288 @@
 package validate
 
 import (
-	// Used for embedding the schema
-	_ "embed"
+	"cmp"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
 
-	"github.com/rs/zerolog"
+	"github.com/BESTSELLER/harpocrates/secrets"
+	"github.com/BESTSELLER/harpocrates/util"
 	"github.com/rs/zerolog/log"
 	"github.com/xeipuuv/gojsonschema"
+	yamlv4 "go.yaml.in/yaml/v4"
 	"sigs.k8s.io/yaml"
 )
 
-//go:embed schema.json
-var Schema string
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// Schemas are the JSON schemas for secrets files by their apiVersion,
+// with the lists of formats and transforms filled in from the secrets package
+var Schemas = loadSchemas()
+
+// Schema is the JSON schema for secrets files of the latest apiVersion
+var Schema = Schemas[util.LatestSpecVersion]
+
+// loadSchemas reads the v1 schema and derives the schema of every later spec version from it
+func loadSchemas() map[string]string {
+	v1, err := schemaFiles.ReadFile("schemas/" + util.SpecVersionV1 + ".json")
+	if err != nil {
+		panic(err)
+	}
+
+	schemas := make(map[string]string, len(util.SpecVersions))
+	for _, version := range util.SpecVersions {
+		var schemaRoot map[string]any
+		if err := json.Unmarshal(v1, &schemaRoot); err != nil {
+			panic(err)
+		}
+		withRegistries(schemaRoot)
+		if version == util.SpecVersionV2 {
+			asV2(schemaRoot)
+		}
+
+		schemaBytes, err := json.Marshal(schemaRoot)
+		if err != nil {
+			panic(err)
+		}
+		schemas[version] = string(schemaBytes)
+	}
+	return schemas
+}
+
+// withRegistries sets the allowed formats and transform steps to the ones known by the secrets package
+func withRegistries(schemaRoot map[string]any) {
+	defs, _ := schemaRoot["$defs"].(map[string]any)
+	format, ok := defs["format"].(map[string]any)
+	if !ok {
+		panic("schema is missing the format definition")
+	}
+	format["enum"] = secrets.Formats()
+
+	transformStep, _ := defs["transformStep"].(map[string]any)
+	anyOf, ok := transformStep["anyOf"].([]any)
+	if !ok || len(anyOf) != 2 {
+		panic("schema is missing the transformStep definition")
+	}
+	anyOf[0].(map[string]any)["enum"] = secrets.TransformNames()
+	withArgument := map[string]any{}
+	for _, name := range secrets.TransformNamesWithArgument() {
+		withArgument[name] = map[string]any{"type": "string"}
+	}
+	anyOf[1].(map[string]any)["properties"] = withArgument
+}
+
+// asV2 changes a v1 schema into the v2 schema, which requires the apiVersion and no longer has the fileName alias of the secrets
+func asV2(schemaRoot map[string]any) {
+	properties, _ := schemaRoot["properties"].(map[string]any)
+	apiVersion, ok := properties["apiVersion"].(map[string]any)
+	if !ok {
+		panic("schema is missing the apiVersion property")
+	}
+	apiVersion["enum"] = []string{util.SpecVersionV2}
+	apiVersion["description"] = "The version of the spec."
+	schemaRoot["required"] = []string{"apiVersion"}
+
+	defs, _ := schemaRoot["$defs"].(map[string]any)
+	secretsObject, _ := defs["secretsObject"].(map[string]any)
+	secretConfigs, ok := secretsObject["patternProperties"].(map[string]any)
+	if !ok {
+		panic("schema is missing the secretsObject definition")
+	}
+	for _, secretConfig := range secretConfigs {
+		secretProperties, _ := secretConfig.(map[string]any)["properties"].(map[string]any)
+		delete(secretProperties, "fileName")
+	}
+}
 
 // SecretsFile validates the secrets file and returns true or false depending on the validation result.
 // Outputs error message if validation fails including what the issue is.
 // Debug message is logged if debug is true and validation succeeded.
 func SecretsFile(fileToValidate string) bool {
+	return logReport(Report{Problems: check(fileToValidate, "")})
+}
+
+// SecretsFileWithIncludes validates the secrets file like SecretsFile, and every spec it includes.
+// specFile is where the secrets file was read from, or empty for an inline spec. The errors name the file they are found in.
+func SecretsFileWithIncludes(fileToValidate string, specFile string) bool {
+	return logReport(Check(fileToValidate, specFile))
+}
+
+// Check validates the secrets file and every spec it includes, and returns the problems found with their position.
+// specFile is where the secrets file was read from, or empty for an inline spec.
+func Check(fileToValidate string, specFile string) Report {
+	return Report{Problems: checkWithIncludes(fileToValidate, specFile, nil)}
+}
+
+// logReport logs the problems of a report and returns whether it is valid
+func logReport(report Report) bool {
+	if report.Valid() {
+		log.Debug().Msg("Secrets file validated successfully!")
+		return true
+	}
+
+	problems := make([]string, len(report.Problems))
+	for i, problem := range report.Problems {
+		problems[i] = problem.String()
+	}
+	log.Error().Strs("validation_errors", problems).Msg("Secrets file failed validation")
+	return false
+}
+
+func checkWithIncludes(fileToValidate string, specFile string, chain []string) []Problem {
+	problems := check(fileToValidate, specFile)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	var spec struct {
+		Include []string `json:"include"`
+	}
+	if err := yaml.Unmarshal([]byte(fileToValidate), &spec); err != nil || len(spec.Include) == 0 {
+		return nil
+	}
+	root, _ := parseNode(fileToValidate)
+
+	chain, err := util.IncludeChain(chain, specFile)
+	if err != nil {
+		return []Problem{newProblem(specFile, root, []string{"include"}, "include", err.Error())}
+	}
+
+	for i, include := range spec.Include {
+		path := util.IncludePath(specFile, include)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			message := fmt.Sprintf("unable to read the included file '%s': %v", include, err)
+			problems = append(problems, newProblem(specFile, root, []string{"include", fmt.Sprint(i)}, "include", message))
+			continue
+		}
+		problems = append(problems, checkWithIncludes(string(data), path, chain)...)
+	}
+	return problems
+}
+
+// check validates a single spec against the schema of its apiVersion
+func check(fileToValidate string, specFile string) []Problem {
+	root, syntaxProblem := parseNode(fileToValidate)
+	if syntaxProblem != nil {
+		syntaxProblem.File = specFile
+		return []Problem{*syntaxProblem}
+	}
+
 	y, err := yaml.YAMLToJSON([]byte(fileToValidate))
 	if err != nil {
-		panic(err)
+		return []Problem{{File: specFile, Rule: "syntax", Message: err.Error()}}
 	}
 
-	schemaLoader := gojsonschema.NewStringLoader(Schema)
+	version, err := util.SpecVersion(fileToValidate)
+	if err != nil {
+		return []Problem{newProblem(specFile, root, []string{"apiVersion"}, "api_version", err.Error())}
+	}
+	if version != util.LatestSpecVersion {
+		log.Debug().Msgf("Secrets file is at apiVersion %s, run harpocrates migrate to upgrade it to %s", version, util.LatestSpecVersion)
+	}
+
+	schemaLoader := gojsonschema.NewStringLoader(Schemas[version])
 	documentLoader := gojsonschema.NewStringLoader(string(y))
 
 	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
 	if err != nil {
-		panic(err.Error())
+		return []Problem{{File: specFile, Rule: "schema", Message: err.Error()}}
 	}
 
-	if result.Valid() {
-		log.Debug().Msg("Secrets file validated successfully!")
-		return true
+	var paths [][]string
+	var problems []Problem
+	for _, desc := range result.Errors() {
+		path, problem := schemaProblem(desc, Schemas[version], specFile, root)
+		paths = append(paths, path)
+		problems = append(problems, problem)
 	}
+	return specificProblems(problems, paths)
+}
 
-	logArr := zerolog.Arr()
-	for _, desc := range result.Errors() {
-		logArr.Str(desc.String())
+// schemaProblem turns a schema validation error into a problem at the option it is about,
+// suggesting the name that was probably meant for unknown options and values
+func schemaProblem(desc gojsonschema.ResultError, schema string, specFile string, root *yamlv4.Node) ([]string, Problem) {
+	path := strings.Split(desc.Context().String("\x00"), "\x00")[1:]
+	field := strings.Join(path, ".")
+	message := desc.Description()
+
+	switch desc.Type() {
+	case "additional_property_not_allowed":
+		option := fmt.Sprint(desc.Details()["property"])
+		message = fmt.Sprintf("unknown option '%s'", option)
+		if suggestion := suggest(option, optionsAt(schema, path)); suggestion != "" {
+			message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		path = append(path, option)
+	case "enum":
+		values := enumValues(desc.Details()["allowed"])
+		message = fmt.Sprintf("must be one of %s", strings.Join(values, ", "))
+		if value, ok := desc.Value().(string); ok {
+			if suggestion := suggest(value, values); suggestion != "" {
+				message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+			}
+		}
 	}
-	log.Error().Array("validation_errors", logArr).Msg("Secrets file failed validation")
-	return false
 
+	if field != "" {
+		message = field + ": " + message
+	}
+	return path, newProblem(specFile, root, path, desc.Type(), message)
+}
+
+// enumValues reads the allowed values of an enum error, which are listed as json
+func enumValues(allowed any) []string {
+	var values []string
+	for _, value := range strings.Split(fmt.Sprint(allowed), ", ") {
+		var text string
+		if err := json.Unmarshal([]byte(value), &text); err == nil {
+			values = append(values, text)
+		}
+	}
+	return values
+}
+
+// specificProblems drops the "must validate at least one schema" problems of options that have a more specific problem,
+// and sorts the problems by their position
+func specificProblems(problems []Problem, paths [][]string) []Problem {
+	isAlternatives := func(problem Problem) bool {
+		return problem.Rule == "number_any_of" || problem.Rule == "number_one_of"
+	}
+
+	var specific []Problem
+	for i, problem := range problems {
+		if isAlternatives(problem) {
+			hasDetails := false
+			for j, path := range paths {
+				if !isAlternatives(problems[j]) && len(path) >= len(paths[i]) && slices.Equal(path[:len(paths[i])], paths[i]) {
+					hasDetails = true
+				}
+			}
+			if hasDetails {
+				continue
+			}
+		}
+		if !slices.Contains(specific, problem) {
+			specific = append(specific, problem)
+		}
+	}
+
+	slices.SortStableFunc(specific, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return specific
+}
+
+// newProblem returns a problem positioned at the node of path in the syntax tree of the spec
+func newProblem(specFile string, root *yamlv4.Node, path []string, rule string, message string) Problem {
+	problem := Problem{File: specFile, Field: strings.Join(path, "."), Rule: rule, Message: message}
+	if node := nodeAt(root, path); node != nil {
+		problem.Line, problem.Column = node.Line, node.Column
+	}
+	return problem
 }
@@ -3,6 +3,7 @@ package validate
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -50,3 +51,125 @@ func TestSecretsFile(t *testing.T) {
 		}
 	}
 }
+
+// Test that included specs are validated too.
+func TestSecretsFileWithIncludes(t *testing.T) {
+	dir := t.TempDir()
+	specs := map[string]string{
+		"invalid.yaml": "uppercase: \"yes\"\nsecrets:\n  - secret/data/shared\n",
+		"app.yaml":     "include:\n  - invalid.yaml\nsecrets:\n  - secret/data/app\n",
+		"a.yaml":       "include:\n  - b.yaml\n",
+		"b.yaml":       "include:\n  - a.yaml\n",
+	}
+	for name, content := range specs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	validSpec := "../test_data/include_spec.yaml"
+	file, err := files.Read(validSpec)
+	if err != nil {
+		t.Fatalf("Failed to read file %s: %v", validSpec, err)
+	}
+	if !SecretsFileWithIncludes(file, validSpec) {
+		t.Errorf("Expected %s to pass validation", validSpec)
+	}
+
+	for _, name := range []string{"app.yaml", "a.yaml"} {
+		specFile := filepath.Join(dir, name)
+		if SecretsFileWithIncludes(specs[name], specFile) {
+			t.Errorf("Expected %s to fail validation", name)
+		}
+	}
+}
+
+// Test that problems are reported at their position, with a suggestion for typos.
+func TestCheck(t *testing.T) {
+	spec := `format: jsn
+secrets:
+  - secret/data/app:
+      saveAsfile: true
+      keys:
+        - key1:
+            alais: url
+            uppercase: "yes"
+`
+	expected := []string{
+		"spec.yaml:1:1: format: must be one of env, ini, json, properties, secret, template, toml, yaml, did you mean 'json'?",
+		"spec.yaml:4:7: secrets.0.secret/data/app: unknown option 'saveAsfile', did you mean 'saveAsFile'?",
+		"spec.yaml:7:13: secrets.0.secret/data/app.keys.0.key1: unknown option 'alais', did you mean 'alias'?",
+		"spec.yaml:8:13: secrets.0.secret/data/app.keys.0.key1.uppercase: Invalid type. Expected: boolean, given: string",
+	}
+
+	report := Check(spec, "spec.yaml")
+	if report.Valid() {
+		t.Fatal("expected the spec to be invalid")
+	}
+	var got []string
+	for _, problem := range report.Problems {
+		got = append(got, problem.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+// Test that malformed specs and unknown versions are reported instead of panicking.
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"secrets:\n  - a: b\n - c\n", "<inline>:3:2: did not find expected key"},
+		{"apiVersion: v3\nsecrets:\n  - secret/data/app\n", "<inline>:1:1: unknown apiVersion 'v3', please use either: v1, v2"},
+		{"uppercase: true\n", "<inline>:1:1: secrets is required"},
+		{`{"secrets": [{"secret/data/app": {"filname": "app.env"}}]}`, "<inline>:1:35: secrets.0.secret/data/app: unknown option 'filname', did you mean 'filename'?"},
+	}
+
+	for _, tt := range tests {
+		report := Check(tt.spec, "")
+		if len(report.Problems) != 1 || report.Problems[0].String() != tt.expected {
+			t.Errorf("expected %q, got %v", tt.expected, report.Problems)
+		}
+	}
+}
+
+// Test that the v2 schema is derived from v1, keeping the fileName alias and secret-level uppercase only where they belong.
+func TestSchemaVersions(t *testing.T) {
+	tests := []struct {
+		spec  string
+		valid bool
+	}{
+		{"secrets:\n  - secret/data/app:\n      fileName: app.env\n      uppercase: true\n", true},
+		{"apiVersion: v1\nsecrets:\n  - secret/data/app:\n      fileName: app.env\n", true},
+		{"apiVersion: v2\nsecrets:\n  - secret/data/app:\n      filename: app.env\n      uppercase: true\n", true},
+		{"apiVersion: v2\nsecrets:\n  - secret/data/app:\n      fileName: app.env\n", false},
+	}
+
+	for _, tt := range tests {
+		if report := Check(tt.spec, ""); report.Valid() != tt.valid {
+			t.Errorf("expected valid to be %v for %q, got %v", tt.valid, tt.spec, report.Problems)
+		}
+	}
+}
+
+// Test that the problems of an included spec name its file.
+func TestCheckIncludes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "shared.yaml"), []byte("secrets:\n  - secret/data/shared:\n      optionl: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	specFile := filepath.Join(dir, "app.yaml")
+
+	report := Check("include:\n  - shared.yaml\n  - missing.yaml\n", specFile)
+	if len(report.Problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", report.Problems)
+	}
+	if problem := report.Problems[0]; problem.File != filepath.Join(dir, "shared.yaml") || problem.Line != 3 || !strings.Contains(problem.Message, "did you mean 'optional'?") {
+		t.Errorf("expected the problem of the included spec, got %v", problem)
+	}
+	if problem := report.Problems[1]; problem.File != specFile || problem.Line != 3 || problem.Rule != "include" {
+		t.Errorf("expected the missing include, got %v", problem)
+	}
+}
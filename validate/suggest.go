@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// suggest returns the candidate closest to name, or an empty string when none is close enough to be a typo of it.
+// Case is ignored, unless two candidates are as close.
+func suggest(name string, candidates []string) string {
+	best, bestDistance, bestCaseDistance := "", len(name)/3+1, 0
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		caseDistance := editDistance(name, candidate)
+		if distance < bestDistance || (distance == bestDistance && best != "" && caseDistance < bestCaseDistance) {
+			best, bestDistance, bestCaseDistance = candidate, distance, caseDistance
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of inserted, removed, changed or swapped adjacent characters needed to turn a into b
+func editDistance(a string, b string) int {
+	first, second := []rune(a), []rune(b)
+	distances := make([][]int, len(first)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(second)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(first); i++ {
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+			distances[i][j] = min(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+			if i > 1 && j > 1 && first[i-1] == second[j-2] && first[i-2] == second[j-1] {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+	return distances[len(first)][len(second)]
+}
+
+// optionsAt returns the names of the options the schema allows in the object at path of a spec
+func optionsAt(schema string, path []string) []string {
+	var schemaRoot map[string]any
+	if err := json.Unmarshal([]byte(schema), &schemaRoot); err != nil {
+		return nil
+	}
+	defs, _ := schemaRoot["$defs"].(map[string]any)
+
+	node := schemaRoot
+	for _, segment := range path {
+		if node = schemaChild(node, segment, defs); node == nil {
+			return nil
+		}
+	}
+
+	var options []string
+	for _, alternative := range schemaAlternatives(node, defs) {
+		properties, _ := alternative["properties"].(map[string]any)
+		for name := range properties {
+			if !slices.Contains(options, name) {
+				options = append(options, name)
+			}
+		}
+	}
+	slices.Sort(options)
+	return options
+}
+
+// schemaChild returns the schema of the option or item named segment of the object or array described by node
+func schemaChild(node map[string]any, segment string, defs map[string]any) map[string]any {
+	for _, alternative := range schemaAlternatives(node, defs) {
+		if properties, ok := alternative["properties"].(map[string]any); ok {
+			if child, ok := properties[segment].(map[string]any); ok {
+				return child
+			}
+		}
+		if patterns, ok := alternative["patternProperties"].(map[string]any); ok {
+			for pattern, child := range patterns {
+				if matched, err := regexp.MatchString(pattern, segment); err == nil && matched {
+					child, _ := child.(map[string]any)
+					return child
+				}
+			}
+		}
+		if child, ok := alternative["additionalProperties"].(map[string]any); ok {
+			return child
+		}
+		if child, ok := alternative["items"].(map[string]any); ok {
+			if _, err := strconv.Atoi(segment); err == nil {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// schemaAlternatives resolves the $ref of a schema and returns it, followed by the schemas of its anyOf
+func schemaAlternatives(node map[string]any, defs map[string]any) []map[string]any {
+	node = resolveRef(node, defs)
+	alternatives := []map[string]any{node}
+	anyOf, _ := node["anyOf"].([]any)
+	for _, alternative := range anyOf {
+		if alternative, ok := alternative.(map[string]any); ok {
+			alternatives = append(alternatives, resolveRef(alternative, defs))
+		}
+	}
+	return alternatives
+}
+
+func resolveRef(node map[string]any, defs map[string]any) map[string]any {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		resolved, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if !ok {
+			return node
+		}
+		node = resolved
+	}
+}
@@ -1,7 +1,6 @@
 package vault
 
 import (
-	"os"
 	"testing"
 
 	"github.com/BESTSELLER/harpocrates/config"
@@ -31,7 +30,7 @@ func TestExtractSecretsWithAlias(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
 		t.Error(err)
 	}
@@ -84,31 +83,22 @@ func TestExtractSecretsWithAliasAndSaveAsFile(t *testing.T) {
 		Client: testClient,
 	}
 
-	// clean up: file should be written using the aliased name, not the original key
-	defer os.Remove("../.tmp/TEST_newKey1") //nolint:errcheck // It's just tests, we don't care
-	defer os.Remove("../.tmp/TEST_key1")    //nolint:errcheck // It's just tests, we don't care
-
 	// act
-	_, err = vaultClient.ExtractSecrets(input, false)
-	if err != nil {
-		t.Error(err)
-	}
-
-	// assert: file must exist with the aliased name
-	content, err := os.ReadFile("../.tmp/TEST_newKey1")
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
-		t.Errorf("expected file ../.tmp/TEST_newKey1 to exist (aliased name), but got error: %v", err)
+		t.Fatal(err)
 	}
+	savedFiles := result[len(result)-1].Files
 
+	// assert: file must be saved with the aliased name
 	expected := "value1"
-	actual := string(content)
-	if expected != actual {
-		t.Errorf("expected file content %q, got %q", expected, actual)
+	if actual := savedFiles["TEST_newKey1"]; actual != expected {
+		t.Errorf("expected file TEST_newKey1 (aliased name) to hold %q, got %v", expected, actual)
 	}
 
-	// assert: file must NOT exist with the original key name
-	if _, err := os.Stat("../.tmp/TEST_key1"); err == nil {
-		t.Errorf("expected no file ../.tmp/TEST_key1 (original key), but it was found")
+	// assert: file must NOT be saved with the original key name
+	if _, found := savedFiles["TEST_key1"]; found {
+		t.Errorf("expected no file TEST_key1 (original key), but it was found")
 	}
 }
 
@@ -134,7 +124,7 @@ func TestExtractSecretsWithKeyLevelUpperCase(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
 		t.Error(err)
 	}
@@ -202,7 +192,7 @@ func TestExtractSecretsWithAliasNested(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
 		t.Error(err)
 	}
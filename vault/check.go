@@ -0,0 +1,246 @@
+package vault
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/BESTSELLER/harpocrates/secrets"
+	"github.com/BESTSELLER/harpocrates/util"
+	"github.com/go-viper/mapstructure/v2"
+	api "github.com/hashicorp/vault/api"
+)
+
+const (
+	// CheckPassed means the secret or key can be read
+	CheckPassed = "pass"
+	// CheckWarning means the secret or key could not be confirmed, or is missing but optional
+	CheckWarning = "warn"
+	// CheckFailed means fetching the spec would fail on the secret or key
+	CheckFailed = "fail"
+)
+
+// CheckResult is the result of checking that a secret, or one of its keys, can be read
+type CheckResult struct {
+	Status  string `json:"status"`
+	Path    string `json:"path"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+// checkTarget is a secret of the spec along with the keys that must exist in it
+type checkTarget struct {
+	path     string
+	optional bool
+	keys     []checkKey
+}
+
+// checkKey is a key that must exist in a secret, optional keys are only a warning when they are missing
+type checkKey struct {
+	name     string
+	optional bool
+}
+
+// CheckSecrets checks that every secret and key of the spec can be read, without fetching their values.
+// The capabilities of the token are asked for every path, and the metadata and subkeys of the KV v2 secrets are read
+// to confirm that they and their keys exist. Secrets without metadata are told apart from missing ones by the version of their mount,
+// and are only a warning when they are not KV v2, as they can't be confirmed to exist without reading their values.
+func (client *API) CheckSecrets(input util.SecretJSON) ([]CheckResult, error) {
+	targets, err := checkTargets(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []CheckResult
+	for _, target := range targets {
+		results = append(results, client.checkSecret(target)...)
+	}
+	return results, nil
+}
+
+// checkTargets lists the secrets of the spec and their keys in the order they are listed
+func checkTargets(input util.SecretJSON) ([]checkTarget, error) {
+	var targets []checkTarget
+	for _, secretEntry := range input.Secrets {
+		secretMapRaw, isMap := secretEntry.(map[string]any)
+		if !isMap {
+			targets = append(targets, checkTarget{path: fmt.Sprintf("%s", secretEntry)})
+			continue
+		}
+
+		secretConfigMap := map[string]util.Secret{}
+		if err := mapstructure.Decode(secretMapRaw, &secretConfigMap); err != nil {
+			return nil, err
+		}
+		for _, secretPath := range slices.Sorted(maps.Keys(secretConfigMap)) {
+			secretConfig := secretConfigMap[secretPath]
+			target := checkTarget{path: secretPath, optional: isTrue(secretConfig.Optional)}
+			for _, key := range secretConfig.RequireKeys {
+				target.keys = append(target.keys, checkKey{name: key})
+			}
+
+			for _, keyEntry := range secretConfig.Keys {
+				if _, isString := keyEntry.(string); isString {
+					target.keys = append(target.keys, checkKey{name: fmt.Sprintf("%s", keyEntry), optional: target.optional})
+					continue
+				}
+
+				secretKeysConfigMap := map[string]util.SecretKeys{}
+				if err := mapstructure.Decode(keyEntry, &secretKeysConfigMap); err != nil {
+					return nil, err
+				}
+				for _, vaultKey := range slices.Sorted(maps.Keys(secretKeysConfigMap)) {
+					keyConfig := secretKeysConfigMap[vaultKey]
+					optional := isTrue(keyConfig.Optional) || keyConfig.Default != nil
+					target.keys = append(target.keys, checkKey{name: vaultKey, optional: optional})
+				}
+			}
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// checkSecret checks the read access to a secret, that it exists and that it has its keys
+func (client *API) checkSecret(target checkTarget) []CheckResult {
+	result := CheckResult{Path: target.path}
+
+	capabilities, err := client.readCapabilities(target.path)
+	if err != nil {
+		result.Status, result.Message = CheckFailed, fmt.Sprintf("unable to check the capabilities of the token: %v", err)
+		return []CheckResult{result}
+	}
+	if !slices.Contains(capabilities, "read") && !slices.Contains(capabilities, "root") {
+		result.Status, result.Message = CheckFailed, fmt.Sprintf("no read access, the token has: %s", strings.Join(capabilities, ", "))
+		return []CheckResult{result}
+	}
+
+	// A secret without metadata is either missing or not a KV v2 secret, which the mount it is in tells apart
+	kvV2 := true
+	metadata, err := client.Client.Logical().Read(metadataPath(target.path))
+	switch {
+	case err != nil:
+		result.Status, result.Message = CheckWarning, fmt.Sprintf("readable, but unable to read its metadata to confirm it exists: %v", err)
+	case metadata == nil:
+		version, err := client.mountKVVersion(target.path)
+		switch {
+		case err != nil:
+			result.Status, result.Message = CheckWarning, fmt.Sprintf("readable, but it has no metadata and its mount can't be read to tell if it exists: %v", err)
+			kvV2 = false
+		case version == "2":
+			result.Status, result.Message = CheckFailed, "not found"
+			if target.optional {
+				result.Status, result.Message = CheckWarning, "not found, it is optional"
+			}
+			return []CheckResult{result}
+		default:
+			result.Status, result.Message = CheckWarning, "readable, but not a KV v2 secret, so it can't be confirmed to exist without reading it"
+			kvV2 = false
+		}
+	case isDeleted(metadata):
+		result.Status, result.Message = CheckFailed, "the current version is deleted"
+		if target.optional {
+			result.Status, result.Message = CheckWarning, result.Message+", it is optional"
+		}
+		return []CheckResult{result}
+	default:
+		version, _ := currentVersion(metadata)
+		result.Status, result.Message = CheckPassed, fmt.Sprintf("readable, version %d", version)
+	}
+
+	results := []CheckResult{result}
+	if len(target.keys) == 0 {
+		return results
+	}
+
+	var structure map[string]any
+	var subkeysErr error
+	if kvV2 {
+		var subkeys *api.Secret
+		subkeys, subkeysErr = client.Client.Logical().ReadWithData(subkeysPath(target.path), map[string][]string{"depth": {"0"}})
+		if subkeysErr == nil && subkeys != nil {
+			structure, _ = subkeys.Data["subkeys"].(map[string]any)
+		}
+	}
+	for _, key := range target.keys {
+		keyResult := CheckResult{Path: target.path, Key: key.name}
+		switch {
+		case !kvV2:
+			keyResult.Status, keyResult.Message = CheckWarning, "not a KV v2 secret, so the key can't be confirmed to exist without reading it"
+		case structure == nil:
+			keyResult.Status, keyResult.Message = CheckWarning, "unable to read the keys of the secret to confirm it exists"
+			if subkeysErr != nil {
+				keyResult.Message += fmt.Sprintf(": %v", subkeysErr)
+			}
+		case hasKey(structure, key.name):
+			keyResult.Status, keyResult.Message = CheckPassed, "exists"
+		case key.optional:
+			keyResult.Status, keyResult.Message = CheckWarning, "not found, it is optional or has a default"
+		default:
+			keyResult.Status, keyResult.Message = CheckFailed, "not found"
+		}
+		results = append(results, keyResult)
+	}
+	return results
+}
+
+// mountKVVersion returns the version of the KV mount a path is in, or an empty string for other secret engines.
+// The mount is read from sys/internal/ui/mounts like the vault CLI does, which any token with access to the path can read.
+func (client *API) mountKVVersion(path string) (string, error) {
+	mount, err := client.Client.Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil {
+		return "", err
+	}
+	if mount == nil {
+		return "", fmt.Errorf("no mount found for '%s'", path)
+	}
+	if mount.Data["type"] != "kv" {
+		return "", nil
+	}
+	options, _ := mount.Data["options"].(map[string]any)
+	if version, _ := options["version"].(string); version != "" {
+		return version, nil
+	}
+	return "1", nil
+}
+
+// readCapabilities returns the capabilities of the token on a secret.
+// Like ReadSecret, a KV v2 path without data after the mount is also accepted.
+func (client *API) readCapabilities(path string) ([]string, error) {
+	capabilities, err := client.Client.Sys().CapabilitiesSelf(path)
+	if err != nil || slices.Contains(capabilities, "read") || kvPath(path, "data") == path {
+		return capabilities, err
+	}
+	withData, err := client.Client.Sys().CapabilitiesSelf(kvPath(path, "data"))
+	if err == nil && slices.Contains(withData, "read") {
+		return withData, nil
+	}
+	return capabilities, nil
+}
+
+// isDeleted reports whether the current version of a KV v2 secret is deleted or destroyed
+func isDeleted(metadata *api.Secret) bool {
+	current, ok := currentVersion(metadata)
+	if !ok {
+		return false
+	}
+	versions, _ := metadata.Data["versions"].(map[string]any)
+	version, _ := versions[fmt.Sprint(current)].(map[string]any)
+	deletionTime, _ := version["deletion_time"].(string)
+	destroyed, _ := version["destroyed"].(bool)
+	return deletionTime != "" || destroyed
+}
+
+// hasKey reports whether a key exists in the subkeys of a secret, which have no values.
+// Items of arrays are not listed in the subkeys, so only the array itself is checked for them.
+func hasKey(subkeys map[string]any, key string) bool {
+	if _, found := secrets.Lookup(subkeys, key); found {
+		return true
+	}
+	if array, _, isItem := strings.Cut(key, "["); isItem && array != "" {
+		_, found := secrets.Lookup(subkeys, array)
+		return found
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BESTSELLER/harpocrates/util"
+	"github.com/hashicorp/vault/api"
+)
+
+func TestCheckTargets(t *testing.T) {
+	input := util.SecretJSON{Secrets: []any{
+		"secret/data/whole",
+		map[string]any{
+			"secret/data/app": map[string]any{
+				"optional":    true,
+				"requireKeys": []any{"url"},
+				"keys": []any{
+					"user",
+					map[string]any{"password": map[string]any{}, "port": map[string]any{"default": 5432}},
+				},
+			},
+		},
+	}}
+
+	expected := []checkTarget{
+		{path: "secret/data/whole"},
+		{path: "secret/data/app", optional: true, keys: []checkKey{
+			{name: "url"},
+			{name: "user", optional: true},
+			{name: "password"},
+			{name: "port", optional: true},
+		}},
+	}
+
+	targets, err := checkTargets(input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("expected %+v, got %+v", expected, targets)
+	}
+}
+
+func TestHasKey(t *testing.T) {
+	subkeys := map[string]any{
+		"user":   nil,
+		"db":     map[string]any{"host": nil},
+		"labels": map[string]any{"app.name": nil},
+		"hosts":  nil,
+	}
+
+	tests := map[string]bool{
+		"user":            true,
+		"db.host":         true,
+		"labels.app.name": true,
+		"hosts[0]":        true,
+		"password":        false,
+		"db.port":         false,
+	}
+
+	for key, expected := range tests {
+		if actual := hasKey(subkeys, key); actual != expected {
+			t.Errorf("expected %v for %q, got %v", expected, key, actual)
+		}
+	}
+}
+
+// TestCheckSecrets tests that secrets and keys are checked against Vault without reading them
+func TestCheckSecrets(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+	})
+	vaultClient := &API{
+		Client: testClient,
+	}
+	// A KV v1 secret has no metadata, and it is not read to confirm it exists
+	if err := testClient.Sys().Mount("kv1", &api.MountInput{Type: "kv", Options: map[string]string{"version": "1"}}); err != nil {
+		t.Fatalf("failed to mount kv1: %s", err)
+	}
+	if _, err := testClient.Logical().Write("kv1/app", map[string]any{"key1": "value1"}); err != nil {
+		t.Fatalf("failed to write secret: %s", err)
+	}
+	input := util.SecretJSON{Secrets: []any{
+		map[string]any{
+			"secret/data/secret": map[string]any{
+				"keys": []any{"key1", map[string]any{"missing_key": map[string]any{"optional": true}}, "other_key"},
+			},
+		},
+		"secret/data/missing",
+		map[string]any{
+			"kv1/app": map[string]any{"keys": []any{"key1", "other_key"}},
+		},
+	}}
+
+	// act
+	results, err := vaultClient.CheckSecrets(input)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []CheckResult{
+		{Status: CheckPassed, Path: "secret/data/secret", Message: "readable, version 1"},
+		{Status: CheckPassed, Path: "secret/data/secret", Key: "key1", Message: "exists"},
+		{Status: CheckWarning, Path: "secret/data/secret", Key: "missing_key", Message: "not found, it is optional or has a default"},
+		{Status: CheckFailed, Path: "secret/data/secret", Key: "other_key", Message: "not found"},
+		{Status: CheckFailed, Path: "secret/data/missing", Message: "not found"},
+		{Status: CheckWarning, Path: "kv1/app", Message: "readable, but not a KV v2 secret, so it can't be confirmed to exist without reading it"},
+		{Status: CheckWarning, Path: "kv1/app", Key: "key1", Message: "not a KV v2 secret, so the key can't be confirmed to exist without reading it"},
+		{Status: CheckWarning, Path: "kv1/app", Key: "other_key", Message: "not a KV v2 secret, so the key can't be confirmed to exist without reading it"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+}
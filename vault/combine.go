@@ -0,0 +1,47 @@
+package vault
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/secrets"
+)
+
+// CombineResults puts the secrets of all outputs in a single Result, regardless of their format, along with the keys in the order they are listed.
+// A key given by more than one output with different values is handled by the duplicates policy, like within one output.
+func CombineResults(allSecrets []Outputs) (secrets.Result, []string, error) {
+	duplicatePolicy, err := secrets.ParseConflictPolicy(config.Config.Duplicates, secrets.DuplicatePolicies, secrets.PolicyWarn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid duplicates policy: %w", err)
+	}
+
+	combined := secrets.NewCollector(duplicatePolicy)
+	for _, output := range allSecrets {
+		for _, key := range outputKeys(output) {
+			if _, err := combined.Set(key, output.Result[key], output.Sources[key]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return combined.Result, combined.Order, nil
+}
+
+// outputKeys returns the keys of the output in the order they are listed in the spec, followed by any keys missing from the order
+func outputKeys(output Outputs) []string {
+	keys := make([]string, 0, len(output.Result))
+	seen := map[string]bool{}
+	for _, key := range output.Order {
+		if _, ok := output.Result[key]; ok && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(output.Result)) {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,63 @@
+package vault
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/BESTSELLER/harpocrates/config"
+	"github.com/BESTSELLER/harpocrates/secrets"
+)
+
+func TestCombineResults(t *testing.T) {
+	allSecrets := []Outputs{
+		{Result: secrets.Result{"USER": "app", "PASSWORD": "first"}, Order: []string{"USER", "PASSWORD"}, Sources: map[string]secrets.Source{"PASSWORD": {Path: "secret/data/a", Key: "password"}}},
+		{Result: secrets.Result{"PASSWORD": "second", "HOST": "db"}, Sources: map[string]secrets.Source{"PASSWORD": {Path: "secret/data/b", Key: "password"}}},
+	}
+
+	tests := []struct {
+		policy   string
+		password string
+		fails    bool
+	}{
+		{"", "second", false},
+		{"warn", "second", false},
+		{"last-wins", "second", false},
+		{"first-wins", "first", false},
+		{"error", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			previous := config.Config.Duplicates
+			config.Config.Duplicates = tt.policy
+			t.Cleanup(func() { config.Config.Duplicates = previous })
+
+			result, order, err := CombineResults(allSecrets)
+			if tt.fails {
+				if err == nil {
+					t.Fatal("expected an error for a key with different values")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result["PASSWORD"] != tt.password || result["USER"] != "app" || result["HOST"] != "db" {
+				t.Errorf("unexpected result %v", result)
+			}
+			if !slices.Equal(order, []string{"USER", "PASSWORD", "HOST"}) {
+				t.Errorf("expected the keys in the order they are listed, got %v", order)
+			}
+		})
+	}
+}
+
+func TestCombineResultsInvalidPolicy(t *testing.T) {
+	previous := config.Config.Duplicates
+	config.Config.Duplicates = "merge"
+	t.Cleanup(func() { config.Config.Duplicates = previous })
+
+	if _, _, err := CombineResults([]Outputs{{Result: secrets.Result{"KEY": "value"}}}); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+}
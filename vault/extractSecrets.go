@@ -2,6 +2,10 @@ package vault
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/BESTSELLER/harpocrates/config"
 	"github.com/BESTSELLER/harpocrates/files"
@@ -17,14 +21,48 @@ type Outputs struct {
 	Filename string         `json:"filename,omitempty"  yaml:"filename,omitempty"`
 	Result   secrets.Result `json:"result,omitempty"    yaml:"result,omitempty"`
 	Owner    *int           `json:"owner,omitempty"     yaml:"owner,omitempty"`
+	Group    *int           `json:"group,omitempty"     yaml:"group,omitempty"`
+	Mode     os.FileMode    `json:"mode,omitempty"      yaml:"mode,omitempty"`
+	Template string         `json:"template,omitempty"  yaml:"template,omitempty"`
+	Nested   bool           `json:"nested,omitempty"    yaml:"nested,omitempty"`
+	// Order holds the keys of Result in the order they are listed in the spec
+	Order []string `json:"order,omitempty"     yaml:"order,omitempty"`
+	// Files holds the values to write with saveAsFile keyed by file name, it is only set on the last output
+	Files secrets.Result `json:"files,omitempty"     yaml:"files,omitempty"`
+	// Sources holds where the value of each key of Result comes from
+	Sources map[string]secrets.Source `json:"sources,omitempty"   yaml:"sources,omitempty"`
+	// SavedFiles describes each file of Files
+	SavedFiles map[string]SavedFile `json:"savedFiles,omitempty" yaml:"savedFiles,omitempty"`
 }
 
-// ExtractSecrets will loop through all the provided secret interfaces
-func (vaultClient *API) ExtractSecrets(input util.SecretJSON, appendToFile bool) ([]Outputs, error) {
+// SavedFile describes a value written to its own file with saveAsFile
+type SavedFile struct {
+	Source  secrets.Source `json:"source"  yaml:"source"`
+	Options files.Options  `json:"options" yaml:"options"`
+}
+
+// The levels of the spec a prefix or uppercase can be set on
+const (
+	levelRoot   = "root"
+	levelSecret = "secret"
+	levelKey    = "key"
+)
+
+// ExtractSecrets will loop through all the provided secret interfaces.
+// Nothing is written, the values of keys with saveAsFile are returned in Files to be written with the outputs.
+func (vaultClient *API) ExtractSecrets(input util.SecretJSON) ([]Outputs, error) {
 	var finalResult []Outputs
-	var result = make(secrets.Result)
+	duplicatePolicy, err := secrets.ParseConflictPolicy(config.Config.Duplicates, secrets.DuplicatePolicies, secrets.PolicyWarn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duplicates policy: %w", err)
+	}
+	var result = secrets.NewCollector(duplicatePolicy)
+	// Values saved as files are collected by their file name, so two keys saved to the same file are duplicates too
+	var savedFiles = secrets.NewCollector(duplicatePolicy)
+	var savedFileSources = map[string]SavedFile{}
 	var currentPrefix = config.Config.Prefix
 	var currentUpperCase = config.Config.UpperCase
+	var prefixFrom, upperCaseFrom = levelRoot, levelRoot
 	var currentFormat = config.Config.Format
 
 	for _, secretEntry := range input.Secrets {
@@ -43,28 +81,58 @@ func (vaultClient *API) ExtractSecrets(input util.SecretJSON, appendToFile bool)
 			}
 
 			for secretPath, secretConfig := range secretConfigMap {
-				setPrefix(secretConfig.Prefix, &currentPrefix)
-				setUpper(secretConfig.UpperCase, &currentUpperCase)
+				prefixFrom = setPrefix(secretConfig.Prefix, &currentPrefix, levelSecret)
+				upperCaseFrom = setUpper(secretConfig.UpperCase, &currentUpperCase, levelSecret)
 				setFormat(secretConfig.Format, &currentFormat)
 
+				if len(secretConfig.RequireKeys) > 0 {
+					found, err := vaultClient.checkRequiredKeys(secretPath, secretConfig)
+					if err != nil {
+						return nil, err
+					}
+					if !found {
+						log.Info().Msgf("Optional secret '%s' not found, skipping.", secretPath)
+						continue
+					}
+				}
+
 				if len(secretConfig.Keys) == 0 {
+					mode, err := files.ParseMode(secretConfig.Mode)
+					if err != nil {
+						return nil, fmt.Errorf("unable to use the mode for '%s': %w", secretPath, err)
+					}
+					selector, err := secrets.NewKeySelector(secretConfig.Exclude, secretConfig.Rename)
+					if err != nil {
+						return nil, fmt.Errorf("unable to select the keys of '%s': %w", secretPath, err)
+					}
+
 					secretValue, err := vaultClient.ReadSecret(secretPath)
 					if err != nil {
-						if secretConfig.Optional != nil && *secretConfig.Optional {
+						if isTrue(secretConfig.Optional) {
 							log.Info().Msgf("Optional secret '%s' not found, skipping.", secretPath)
 							continue
 						}
 						return nil, err
 					}
-					var thisResult = make(secrets.Result)
-					for key, value := range secretValue {
-						thisResult.Add(key, value, currentPrefix, currentUpperCase)
+					secretValue, err = selector.Select(secretValue)
+					if err != nil {
+						return nil, fmt.Errorf("unable to select the keys of '%s': %w", secretPath, err)
+					}
+					var thisResult = secrets.NewCollector(duplicatePolicy)
+					for _, key := range slices.Sorted(maps.Keys(secretValue)) {
+						if _, err := thisResult.Add(key, secretValue[key], currentPrefix, currentUpperCase, secrets.Source{Path: secretPath, Key: key, PrefixFrom: prefixFrom, UpperCaseFrom: upperCaseFrom}); err != nil {
+							return nil, err
+						}
 					}
 
-					finalResult = append(finalResult, Outputs{Format: currentFormat, Filename: secretConfig.FileName, Result: thisResult, Owner: secretConfig.Owner})
+					finalResult = append(finalResult, Outputs{Format: currentFormat, Filename: secretConfig.FileName, Result: thisResult.Result, Order: thisResult.Order, Sources: thisResult.Sources, Owner: secretConfig.Owner, Group: secretConfig.Group, Mode: mode, Template: getTemplate(secretConfig.Template), Nested: getNested(secretConfig.Nested)})
 					continue
 				}
 
+				if len(secretConfig.Exclude) > 0 || len(secretConfig.Rename) > 0 {
+					log.Warn().Msgf("The exclude and rename of '%s' are only used when no keys are listed, ignoring them.", secretPath)
+				}
+
 				for _, keyEntry := range secretConfig.Keys {
 					// If the key is just a secret path, then it will read that from Vault, otherwise:
 					if _, isString := keyEntry.(string); !isString {
@@ -75,86 +143,177 @@ func (vaultClient *API) ExtractSecrets(input util.SecretJSON, appendToFile bool)
 						}
 
 						for vaultKey, keyConfig := range secretKeysConfigMap {
-							setPrefix(keyConfig.Prefix, &currentPrefix)
-							setUpper(keyConfig.UpperCase, &currentUpperCase)
+							keyPrefixFrom := setPrefix(keyConfig.Prefix, &currentPrefix, levelKey)
+							keyUpperCaseFrom := setUpper(keyConfig.UpperCase, &currentUpperCase, levelKey)
+							source := secrets.Source{Path: secretPath, Key: vaultKey, PrefixFrom: keyPrefixFrom, UpperCaseFrom: keyUpperCaseFrom}
 
 							keyName := vaultKey
 							if keyConfig.Alias != "" {
 								keyName = keyConfig.Alias
 							}
 
-							if keyConfig.SaveAsFile != nil {
-								secretValue, err := vaultClient.ReadSecretKey(secretPath, vaultKey)
+							secretValue, found, err := vaultClient.readKey(secretPath, vaultKey, keyConfig)
+							if err != nil {
+								return nil, err
+							}
+							if !found {
+								continue
+							}
+
+							if isTrue(keyConfig.SaveAsFile) {
+								mode, err := files.ParseMode(keyConfig.Mode)
 								if err != nil {
-									if *keyConfig.Optional {
-										log.Info().Msgf("Optional secret key '%s' not found in '%s', skipping.", vaultKey, secretPath)
-										continue
-									}
-									return nil, err
+									return nil, fmt.Errorf("unable to use the mode for the key '%s' in '%s': %w", vaultKey, secretPath, err)
 								}
-								if *keyConfig.SaveAsFile {
-									files.Write(input.Output, secrets.ToUpperOrNotToUpper(fmt.Sprintf("%s%s", currentPrefix, keyName), &currentUpperCase), secretValue, nil, appendToFile)
-								} else {
-									result.Add(keyName, secretValue, currentPrefix, currentUpperCase)
+								fileOptions := files.Options{Group: keyConfig.Group, Mode: mode}
+								fileName := secrets.ToUpperOrNotToUpper(fmt.Sprintf("%s%s", currentPrefix, keyName), &currentUpperCase)
+								if keyConfig.FileName != "" {
+									fileName = keyConfig.FileName
+									fileOptions.ExactName = true
 								}
-							} else {
-								secretValue, err := vaultClient.ReadSecretKey(secretPath, vaultKey)
+								source.Prefix, source.UpperCase = currentPrefix, currentUpperCase
+								added, err := savedFiles.Set(fileName, secretValue, source)
 								if err != nil {
-									if *keyConfig.Optional {
-										log.Info().Msgf("Optional secret key '%s' not found in '%s', skipping.", vaultKey, secretPath)
-										continue
-									}
 									return nil, err
 								}
-								result.Add(keyName, secretValue, currentPrefix, currentUpperCase)
+								if added {
+									savedFileSources[fileName] = SavedFile{Source: source, Options: fileOptions}
+								}
+							} else if _, err := result.Add(keyName, secrets.AsText(secretValue), currentPrefix, currentUpperCase, source); err != nil {
+								return nil, err
 							}
-							setPrefix(secretConfig.Prefix, &currentPrefix)
-							setUpper(secretConfig.UpperCase, &currentUpperCase)
+							setPrefix(secretConfig.Prefix, &currentPrefix, levelSecret)
+							setUpper(secretConfig.UpperCase, &currentUpperCase, levelSecret)
 						}
 					} else {
 						secretValue, err := vaultClient.ReadSecretKey(secretPath, fmt.Sprintf("%s", keyEntry))
 						if err != nil {
-							if *secretConfig.Optional {
+							if isTrue(secretConfig.Optional) {
 								log.Info().Msgf("Optional secret key '%s' not found in '%s', skipping.", keyEntry, secretPath)
 								continue
 							}
 							return nil, err
 						}
-						result.Add(fmt.Sprintf("%s", keyEntry), secretValue, currentPrefix, currentUpperCase)
+						vaultKey := fmt.Sprintf("%s", keyEntry)
+						if _, err := result.Add(vaultKey, secretValue, currentPrefix, currentUpperCase, secrets.Source{Path: secretPath, Key: vaultKey, PrefixFrom: prefixFrom, UpperCaseFrom: upperCaseFrom}); err != nil {
+							return nil, err
+						}
 					}
 				}
-				setPrefix(config.Config.Prefix, &currentPrefix)
-				setUpper(secretConfig.UpperCase, &currentUpperCase)
+				prefixFrom = setPrefix(config.Config.Prefix, &currentPrefix, levelRoot)
+				upperCaseFrom = setUpper(secretConfig.UpperCase, &currentUpperCase, levelSecret)
 				setFormat(secretConfig.Format, &currentFormat)
 			}
 		} else {
-			secretValue, err := vaultClient.ReadSecret(fmt.Sprintf("%s", secretEntry))
+			secretPath := fmt.Sprintf("%s", secretEntry)
+			secretValue, err := vaultClient.ReadSecret(secretPath)
 			if err != nil {
 				return nil, err
 			}
-			for key, value := range secretValue {
-				result.Add(key, value, currentPrefix, currentUpperCase)
+			for _, key := range slices.Sorted(maps.Keys(secretValue)) {
+				if _, err := result.Add(key, secretValue[key], currentPrefix, currentUpperCase, secrets.Source{Path: secretPath, Key: key, PrefixFrom: prefixFrom, UpperCaseFrom: upperCaseFrom}); err != nil {
+					return nil, err
+				}
 			}
 		}
 	}
 
-	finalResult = append(finalResult, Outputs{Format: config.Config.Format, Filename: "", Result: result})
+	finalResult = append(finalResult, Outputs{Format: config.Config.Format, Filename: "", Result: result.Result, Order: result.Order, Sources: result.Sources, Template: config.Config.Template, Nested: config.Config.Nested, Files: savedFiles.Result, SavedFiles: savedFileSources})
 	return finalResult, nil
 }
 
-func setPrefix(potentialPrefix string, currentPrefix *string) {
+// readKey reads a key configured in the spec and transforms it. A key that is missing from the secret gets its default,
+// or is skipped with false if it is optional. Errors reading the secret itself are only skipped for optional keys,
+// so a default never hides a denied or missing path. The value must match the pattern of the key.
+func (vaultClient *API) readKey(secretPath string, vaultKey string, keyConfig util.SecretKeys) (any, bool, error) {
+	secret, err := vaultClient.ReadSecret(secretPath)
+	if err != nil {
+		if isTrue(keyConfig.Optional) {
+			log.Info().Msgf("Optional secret key '%s' not found in '%s', skipping.", vaultKey, secretPath)
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf(keyNotFound, vaultKey, secretPath, err)
+	}
+
+	secretValue, found := secrets.Lookup(secret, vaultKey)
+	if !found {
+		switch {
+		case keyConfig.Default != nil:
+			log.Debug().Msgf("Secret key '%s' not found in '%s', using its default.", vaultKey, secretPath)
+			secretValue = keyConfig.Default
+		case isTrue(keyConfig.Optional):
+			log.Info().Msgf("Optional secret key '%s' not found in '%s', skipping.", vaultKey, secretPath)
+			return nil, false, nil
+		default:
+			return nil, false, fmt.Errorf(keyNotFound, vaultKey, secretPath, nil)
+		}
+	}
+
+	secretValue, err = secrets.Transform(secretValue, keyConfig.Transform)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to transform the key '%s' in '%s': %w", vaultKey, secretPath, err)
+	}
+	if keyConfig.Pattern != "" {
+		if err := secrets.MatchPattern(secretValue, keyConfig.Pattern); err != nil {
+			return nil, false, fmt.Errorf("the key '%s' in '%s' is not valid: %w", vaultKey, secretPath, err)
+		}
+	}
+	return secretValue, true, nil
+}
+
+// checkRequiredKeys returns an error listing the requireKeys the secret doesn't have in Vault.
+// It returns false if the secret is optional and not found.
+func (vaultClient *API) checkRequiredKeys(secretPath string, secretConfig util.Secret) (bool, error) {
+	secret, err := vaultClient.ReadSecret(secretPath)
+	if err != nil {
+		if isTrue(secretConfig.Optional) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if missing := secrets.MissingKeys(secret, secretConfig.RequireKeys); len(missing) > 0 {
+		return false, fmt.Errorf("the secret '%s' is missing the required keys: %s", secretPath, strings.Join(missing, ", "))
+	}
+	return true, nil
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
+
+// setPrefix sets the prefix given on the level, or the root prefix, and returns the level the prefix comes from
+func setPrefix(potentialPrefix string, currentPrefix *string, level string) string {
 	if potentialPrefix != "" {
 		*currentPrefix = potentialPrefix
-	} else {
-		*currentPrefix = config.Config.Prefix
+		return level
 	}
+	*currentPrefix = config.Config.Prefix
+	return levelRoot
 }
-func setUpper(potentialUpper *bool, currentUpper *bool) {
+
+// setUpper sets the uppercase given on the level, or the root uppercase, and returns the level the uppercase comes from
+func setUpper(potentialUpper *bool, currentUpper *bool, level string) string {
 	if potentialUpper != nil {
 		*currentUpper = *potentialUpper
-	} else {
-		*currentUpper = config.Config.UpperCase
+		return level
+	}
+	*currentUpper = config.Config.UpperCase
+	return levelRoot
+}
+
+func getTemplate(potentialTemplate string) string {
+	if potentialTemplate != "" {
+		return potentialTemplate
+	}
+	return config.Config.Template
+}
+
+func getNested(potentialNested *bool) bool {
+	if potentialNested != nil {
+		return *potentialNested
 	}
+	return config.Config.Nested
 }
 
 func setFormat(potentialFormat string, currentFormat *string) {
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/BESTSELLER/harpocrates/config"
 	"github.com/BESTSELLER/harpocrates/files"
+	"github.com/BESTSELLER/harpocrates/secrets"
 	"github.com/BESTSELLER/harpocrates/util"
 	"github.com/hashicorp/vault/api"
 	"github.com/testcontainers/testcontainers-go/modules/vault"
@@ -81,7 +84,7 @@ func TestExtractSecretsWithOptionalSecret(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 
 	// assert
 	if err != nil {
@@ -118,7 +121,7 @@ func TestExtractSecretsWithOptionalSecretKey(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 
 	// assert
 	if err != nil {
@@ -155,7 +158,7 @@ func TestExtractSecretsWithOptionalSecretPlainKey(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 
 	// assert
 	if err != nil {
@@ -189,7 +192,7 @@ func TestExtractSecretsWithNonOptional(t *testing.T) {
 	}
 
 	// act
-	_, err = vaultClient.ExtractSecrets(input, false)
+	_, err = vaultClient.ExtractSecrets(input)
 
 	// assert
 	if err == nil {
@@ -220,7 +223,7 @@ func TestExtractSecretsWithFormatAsExpected(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
 		t.Error(err)
 	}
@@ -260,7 +263,7 @@ func TestExtractSecretsAsExpected(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
 		t.Error(err)
 	}
@@ -299,7 +302,7 @@ func TestExtractSecretsWithPrefixAsExpected(t *testing.T) {
 	}
 
 	// act
-	result, err := vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
 		t.Error(err)
 	}
@@ -339,25 +342,355 @@ func TestExtractSecretsSaveAsFileAsExpected(t *testing.T) {
 	}
 
 	// act
-	_, err = vaultClient.ExtractSecrets(input, false)
+	result, err := vaultClient.ExtractSecrets(input)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+
+	// assert
+	expected := "value1"
+	actual := result[len(result)-1].Files["TEST_key1"]
+
+	if expected != actual {
+		t.Errorf("expected %q, got %v", expected, actual)
 	}
 
-	// clean up
-	defer os.Remove("../.tmp/TEST_key1") //nolint:errcheck // It's just tests, we don't care
+}
+
+// TestExtractSecretsWithDefaultAndPattern tests that a missing key gets its default and a matching value passes its pattern
+func TestExtractSecretsWithDefaultAndPattern(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+	})
+
+	// define input
+	data, err := files.Read("../test_data/default_and_pattern.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	input := util.ReadInput(data)
+
+	// mock prefix
+	config.Config.Prefix = input.Prefix
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	result, err := vaultClient.ExtractSecrets(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// assert
-	content, err := os.ReadFile("../.tmp/TEST_key1")
+	expected := fmt.Sprintf("%v", map[string]any{"key1": "value1", "missing_key": "fallback"})
+	actual := fmt.Sprintf("%v", result[0].Result)
+
+	if expected != actual {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+// TestExtractSecretsWithDefaultForMissingSecret tests that a default is not used when the secret itself can't be read
+func TestExtractSecretsWithDefaultForMissingSecret(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+	})
+
+	// define input
+	data, err := files.Read("../test_data/default_missing_secret.yaml")
 	if err != nil {
-		t.Errorf("could not read file: %v", err)
+		t.Fatalf("Failed to read test data: %v", err)
 	}
+	input := util.ReadInput(data)
 
-	expected := "value1"
-	actual := string(content)
+	// mock prefix
+	config.Config.Prefix = input.Prefix
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	_, err = vaultClient.ExtractSecrets(input)
+
+	// assert
+	if err == nil {
+		t.Error("expected an error for a default in a secret that does not exist")
+	}
+}
+
+// TestExtractSecretsWithMissingRequiredKeys tests that a secret without its required keys fails
+func TestExtractSecretsWithMissingRequiredKeys(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+	})
+
+	// define input
+	data, err := files.Read("../test_data/required_keys_missing.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	input := util.ReadInput(data)
+
+	// mock prefix
+	config.Config.Prefix = input.Prefix
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	_, err = vaultClient.ExtractSecrets(input)
+
+	// assert
+	expected := "the secret 'secret/data/secret' is missing the required keys: missing_key"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+// TestExtractSecretsWithExcludeAndRename tests that excluded keys are left out and the others are renamed before the prefix and uppercase
+func TestExtractSecretsWithExcludeAndRename(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+		config.Config.UpperCase = false
+	})
+
+	// define input
+	data, err := files.Read("../test_data/exclude_and_rename.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	input := util.ReadInput(data)
+
+	// mock prefix
+	config.Config.Prefix = input.Prefix
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	result, err := vaultClient.ExtractSecrets(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	expected := fmt.Sprintf("%v", map[string]any{"APP_SETTING_1": "value1", "APP_SETTING_2": "value2", "APP_SETTING_3": "value3"})
+	actual := fmt.Sprintf("%v", result[0].Result)
 
 	if expected != actual {
 		t.Errorf("expected %q, got %q", expected, actual)
 	}
+}
+
+// TestExtractSecretsWithDuplicateKeys tests that two keys giving the same key in one output fail with the duplicates policy error
+func TestExtractSecretsWithDuplicateKeys(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+		config.Config.Duplicates = ""
+	})
+
+	// define input
+	data, err := files.Read("../test_data/duplicate_keys.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	input := util.ReadInput(data)
+
+	// mock prefix
+	config.Config.Prefix = input.Prefix
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	_, err = vaultClient.ExtractSecrets(input)
+
+	// assert
+	expected := "the key 'password' is set by both the key 'key1' in 'secret/data/secret' and the key 'key2' in 'secret/data/secret'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+// TestExtractSecretsWithDuplicateSavedFiles tests that two keys saved to the same file are handled by the duplicates policy
+func TestExtractSecretsWithDuplicateSavedFiles(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+		config.Config.Duplicates = ""
+	})
+
+	// define input
+	data, err := files.Read("../test_data/duplicate_saved_files.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	input := util.ReadInput(data)
+
+	// mock prefix
+	config.Config.Prefix = input.Prefix
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	_, err = vaultClient.ExtractSecrets(input)
+
+	// assert
+	expected := "the key 'secret.txt' is set by both the key 'key1' in 'secret/data/secret' and the key 'key2' in 'secret/data/secret'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+// TestExtractSecretsSources tests that each key tells where it comes from and which level its prefix is set on
+func TestExtractSecretsSources(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+	})
+
+	// define input
+	data, err := files.Read("../test_data/keys_with_prefix.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	input := util.ReadInput(data)
+
+	// mock prefix
+	config.Config.Prefix = input.Prefix
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	result, err := vaultClient.ExtractSecrets(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	expected := map[string]secrets.Source{
+		"PRE_key1":  {Path: "secret/data/secret", Key: "key1", Prefix: "PRE_", PrefixFrom: "key", UpperCaseFrom: "root"},
+		"FIX_key2":  {Path: "secret/data/secret", Key: "key2", Prefix: "FIX_", PrefixFrom: "key", UpperCaseFrom: "root"},
+		"TEST_key3": {Path: "secret/data/secret", Key: "key3", Prefix: "TEST_", PrefixFrom: "root", UpperCaseFrom: "root"},
+	}
+	if actual := result[len(result)-1].Sources; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestSetPrefix(t *testing.T) {
+	config.Config.Prefix = "ROOT_"
+	t.Cleanup(func() { config.Config.Prefix = "" })
+
+	var prefix string
+	if from := setPrefix("KEY_", &prefix, levelKey); from != levelKey || prefix != "KEY_" {
+		t.Errorf("expected the prefix 'KEY_' from %s, got '%s' from %s", levelKey, prefix, from)
+	}
+	if from := setPrefix("", &prefix, levelKey); from != levelRoot || prefix != "ROOT_" {
+		t.Errorf("expected the prefix 'ROOT_' from %s, got '%s' from %s", levelRoot, prefix, from)
+	}
+}
+
+func TestSetUpper(t *testing.T) {
+	config.Config.UpperCase = true
+	t.Cleanup(func() { config.Config.UpperCase = false })
+
+	var upper bool
+	if from := setUpper(new(bool), &upper, levelSecret); from != levelSecret || upper {
+		t.Errorf("expected uppercase false from %s, got %t from %s", levelSecret, upper, from)
+	}
+	if from := setUpper(nil, &upper, levelSecret); from != levelRoot || !upper {
+		t.Errorf("expected uppercase true from %s, got %t from %s", levelRoot, upper, from)
+	}
+}
 
+// TestExtractSecretsAppendTwice tests that fetching the same spec twice with append gives the same files,
+// also for outputs that can't be merged and keys saved as files
+func TestExtractSecretsAppendTwice(t *testing.T) {
+	// arrange
+	setupVault(t)
+	output := t.TempDir()
+	config.Config.Output = output
+	t.Cleanup(func() {
+		testClient = nil
+		config.Config.Output = ""
+	})
+
+	// define input
+	data, err := files.Read("../test_data/append_twice.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	input := util.ReadInput(data)
+
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	run := func() map[string]string {
+		allSecrets, err := vaultClient.ExtractSecrets(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, output := range allSecrets {
+			formatter, _ := secrets.GetFormatter(output.Format)
+			content, err := formatter(output.Result, secrets.FormatOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			files.Write(config.Config.Output, "secrets.toml", content, files.Options{}, true)
+			for fileName, saved := range output.SavedFiles {
+				files.Write(config.Config.Output, fileName, output.Files[fileName], saved.Options, true)
+			}
+		}
+		files.Commit()
+
+		written := map[string]string{}
+		entries, err := os.ReadDir(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			content, err := os.ReadFile(filepath.Join(output, entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			written[entry.Name()] = string(content)
+		}
+		return written
+	}
+
+	// act
+	first := run()
+	second := run()
+
+	// assert
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same files after the second fetch, got %q and %q", first, second)
+	}
+	if first["key1"] != "value1" {
+		t.Errorf("expected the saved file to hold %q, got %q", "value1", first["key1"])
+	}
 }
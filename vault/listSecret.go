@@ -3,8 +3,42 @@ package vault
 import (
 	"fmt"
 	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
 )
 
+// PathLister lists the mounts and paths of Vault, it is implemented by API
+type PathLister interface {
+	ListKeys(path string) ([]string, error)
+	ListSecretEngines() ([]string, error)
+	GetEngineSubPath(mountPath string) (string, error)
+}
+
+// ListPath lists what can follow a path written in a spec, e.g. the secrets and folders under kv/data/app/.
+// An empty path lists the mounts. KV v2 paths are listed through their metadata, and the sub path of an engine,
+// e.g. data/ for a KV v2 mount, is added to the listing of its mount.
+func ListPath(lister PathLister, basePath string) ([]string, error) {
+	if basePath == "" {
+		return lister.ListSecretEngines()
+	}
+
+	tokens, err := lister.ListKeys(strings.Replace(basePath, "/data/", "/metadata/", 1))
+	if err != nil {
+		return nil, err
+	}
+
+	subPath, err := lister.GetEngineSubPath(basePath)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Unable to find the sub path of '%s'", basePath)
+		return tokens, nil
+	}
+	if subPath == "" || slices.Contains(tokens, strings.TrimPrefix(subPath, basePath)) {
+		return tokens, nil
+	}
+	return append(tokens, strings.TrimPrefix(subPath, basePath)), nil
+}
+
 // ListKeys lists the sub-paths or secrets at a specific Vault path.
 func (client *API) ListKeys(path string) ([]string, error) {
 	secretValues, err := client.Client.Logical().List(path)
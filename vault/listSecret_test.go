@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeLister lists the paths it is given, and gives kv/ the data/ sub path of a KV v2 mount
+type fakeLister struct {
+	paths map[string][]string
+}
+
+func (f fakeLister) ListKeys(path string) ([]string, error) {
+	if path == "broken/" {
+		return nil, errors.New("permission denied")
+	}
+	return f.paths[path], nil
+}
+
+func (f fakeLister) ListSecretEngines() ([]string, error) {
+	return []string{"kv/", "secret/"}, nil
+}
+
+func (f fakeLister) GetEngineSubPath(mountPath string) (string, error) {
+	if mountPath == "kv/" {
+		return "kv/data/", nil
+	}
+	return "", nil
+}
+
+func TestListPath(t *testing.T) {
+	lister := fakeLister{paths: map[string][]string{
+		"kv/metadata/":     {"app/"},
+		"kv/metadata/app/": {"dev", "prod"},
+		"secret/":          {"data/", "other/"},
+	}}
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"", []string{"kv/", "secret/"}},
+		{"kv/", []string{"data/"}},
+		{"kv/data/", []string{"app/"}},
+		{"kv/data/app/", []string{"dev", "prod"}},
+		{"secret/", []string{"data/", "other/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := ListPath(lister, tt.path)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := ListPath(lister, "broken/"); err == nil {
+		t.Error("expected an error listing broken/")
+	}
+}
@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	api "github.com/hashicorp/vault/api"
+)
+
+// SecretVersion returns the current version of a KV v2 secret.
+// It reads the metadata of the secret, so the value itself is not fetched.
+func (client *API) SecretVersion(path string) (int, error) {
+	metadata, err := client.Client.Logical().Read(metadataPath(path))
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the metadata of '%s': %w", path, err)
+	}
+	if metadata == nil {
+		return 0, fmt.Errorf("no metadata found for '%s', only KV v2 secrets have versions", path)
+	}
+
+	current, ok := currentVersion(metadata)
+	if !ok {
+		return 0, fmt.Errorf("no version found in the metadata of '%s'", path)
+	}
+	return current, nil
+}
+
+// SecretKeys returns the top level keys of a secret sorted by name.
+// The keys of KV v2 secrets are read from their subkeys, so the values are not fetched. Other secrets are read to list their keys.
+func (client *API) SecretKeys(path string) ([]string, error) {
+	subkeys, err := client.Client.Logical().ReadWithData(subkeysPath(path), map[string][]string{"depth": {"1"}})
+	if err == nil && subkeys != nil {
+		if structure, ok := subkeys.Data["subkeys"].(map[string]any); ok {
+			return slices.Sorted(maps.Keys(structure)), nil
+		}
+	}
+
+	secret, err := client.ReadSecret(path)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(secret)), nil
+}
+
+// currentVersion returns the current version in the metadata of a KV v2 secret
+func currentVersion(metadata *api.Secret) (int, bool) {
+	switch version := metadata.Data["current_version"].(type) {
+	case json.Number:
+		current, err := version.Int64()
+		return int(current), err == nil
+	case float64:
+		return int(version), true
+	default:
+		return 0, false
+	}
+}
+
+// metadataPath returns the metadata path of a KV v2 secret, e.g. secret/data/app becomes secret/metadata/app.
+// Like ReadSecret, a path without data after the mount is also accepted.
+func metadataPath(path string) string {
+	return kvPath(path, "metadata")
+}
+
+// subkeysPath returns the path listing the keys of a KV v2 secret without their values, e.g. secret/data/app becomes secret/subkeys/app
+func subkeysPath(path string) string {
+	return kvPath(path, "subkeys")
+}
+
+// kvPath returns the path of a KV v2 secret under another section of its mount than data
+func kvPath(path string, section string) string {
+	splitPath := strings.Split(path, "/")
+	if len(splitPath) > 1 && splitPath[1] == "data" {
+		splitPath[1] = section
+		return strings.Join(splitPath, "/")
+	}
+	return strings.Join(append([]string{splitPath[0], section}, splitPath[1:]...), "/")
+}
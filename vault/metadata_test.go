@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadataPath(t *testing.T) {
+	tests := map[string]string{
+		"secret/data/app/dev": "secret/metadata/app/dev",
+		"secret/app/dev":      "secret/metadata/app/dev",
+	}
+
+	for path, expected := range tests {
+		if actual := metadataPath(path); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, path, actual)
+		}
+	}
+}
+
+func TestSubkeysPath(t *testing.T) {
+	tests := map[string]string{
+		"secret/data/app/dev": "secret/subkeys/app/dev",
+		"secret/app/dev":      "secret/subkeys/app/dev",
+	}
+
+	for path, expected := range tests {
+		if actual := subkeysPath(path); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, path, actual)
+		}
+	}
+}
+
+// TestSecretVersion tests that the version changes when the secret is written
+func TestSecretVersion(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+	})
+	vaultClient := &API{
+		Client: testClient,
+	}
+	path := "secret/data/secret"
+
+	// act
+	before, err := vaultClient.SecretVersion(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = testClient.Logical().Write(path, map[string]any{"data": map[string]any{"key1": "rotated"}})
+	if err != nil {
+		t.Fatalf("failed to write secret: %s", err)
+	}
+	after, err := vaultClient.SecretVersion(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// assert
+	if after != before+1 {
+		t.Errorf("expected version %d, got %d", before+1, after)
+	}
+}
+
+// TestSecretKeys tests that the keys of a secret are listed sorted
+func TestSecretKeys(t *testing.T) {
+	// arrange
+	setupVault(t)
+	t.Cleanup(func() {
+		testClient = nil
+	})
+	vaultClient := &API{
+		Client: testClient,
+	}
+
+	// act
+	keys, err := vaultClient.SecretKeys("secret/data/secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	expected := []string{"key1", "key2", "key3", "key4", "key5"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
@@ -3,8 +3,9 @@ package vault
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
+
+	"github.com/BESTSELLER/harpocrates/secrets"
 )
 
 const keyNotFound = "the key '%s' was not found in the path '%s': %v"
@@ -59,15 +60,7 @@ func (client *API) ReadSecret(path string) (map[string]any, error) {
 
 // ReadSecretKey retrieves a value from a Vault secret at a specific path.
 //
-// It supports accessing nested keys using dot notation or array brackets.
-//
-// Usage examples (assuming JSON structure):
-//   - "simpleKey"           -> {"simpleKey": "value"}
-//   - "nested.key"          -> {"nested": {"key": "value"}}
-//   - "array[0]"            -> {"array": ["value", "other"]}
-//   - "mixed.array[0].key"  -> {"mixed": {"array": [{"key": "value"}]}}
-//   - "key.with.dots"       -> {"key": {"with.dots": "value"}}
-//   - "key.with.dots.child" -> {"key": {"with.dots": {"child": "value"}}}
+// It supports accessing nested keys using dot notation or array brackets, see secrets.Lookup.
 func (client *API) ReadSecretKey(path string, secretKey string) (any, error) {
 	secret, err := client.ReadSecret(path)
 	if secret == nil {
@@ -77,61 +70,9 @@ func (client *API) ReadSecretKey(path string, secretKey string) (any, error) {
 		return "", err
 	}
 
-	// 1. Literal match
-	// Check if the secretKey exists exactly as-is in the top-level secret.
-	// This handles keys that naturally contain dots or brackets without needing traversal.
-	if literalValue, keyExists := secret[secretKey]; keyExists {
-		return literalValue, nil
-	}
-
-	// 2. Traversal configuration
-	// Normalize access syntax by replacing array brackets with dots to unify the traversal loop.
-	// e.g., "users[0].name" becomes "users.0.name"
-	normalizedKey := strings.ReplaceAll(secretKey, "[", ".")
-	normalizedKey = strings.ReplaceAll(normalizedKey, "]", "")
-	keys := strings.Split(normalizedKey, ".")
-
-	var current any = secret
-	for i := 0; i < len(keys); i++ {
-		keySegment := keys[i]
-
-		if currentMap, isMap := current.(map[string]any); isMap {
-			// Check for exact match of the current segment in the map
-			if mapValue, exists := currentMap[keySegment]; exists {
-				current = mapValue
-				continue
-			}
-
-			// Attempt to match keys with dots (merging segments)
-			// This handles cases where a JSON key contains dots (e.g., "labels.app")
-			// but isn't necessarily at the end of the path.
-			matchFound := false
-			for j := i + 1; j < len(keys); j++ {
-				// Construct candidate key from segments i to j (inclusive)
-				candidate := strings.Join(keys[i:j+1], ".")
-				if mapValue, exists := currentMap[candidate]; exists {
-					current = mapValue
-					i = j // Advance the outer loop index
-					matchFound = true
-					break
-				}
-			}
-
-			if matchFound {
-				continue
-			}
-		}
-
-		if currentSlice, isSlice := current.([]any); isSlice {
-			// Handle array index access (e.g., from "users[0]" -> "0")
-			if sliceIndex, err := strconv.Atoi(keySegment); err == nil {
-				if sliceIndex >= 0 && sliceIndex < len(currentSlice) {
-					current = currentSlice[sliceIndex]
-					continue
-				}
-			}
-		}
+	value, found := secrets.Lookup(secret, secretKey)
+	if !found {
 		return "", fmt.Errorf(keyNotFound, secretKey, path, nil)
 	}
-	return current, nil
+	return value, nil
 }